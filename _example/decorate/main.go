@@ -15,10 +15,10 @@ var (
 	actions = []string{"downloading...", "compiling source...", "fetching...", "committing work..."}
 )
 
-func calcStepsDur() func(int, int, time.Time, bool) string {
-	f := cmpb.CalcDur()
+func calcStepsDur(clock cmpb.Clock) func(int64, int64, time.Time, bool) string {
+	f := cmpb.CalcDur(clock)
 
-	return func(curr, total int, start time.Time, stopped bool) string {
+	return func(curr, total int64, start time.Time, stopped bool) string {
 		return fmt.Sprintf("%s %s", cmpb.CalcSteps(curr, total, start, stopped),
 			f(curr, total, start, stopped))
 	}
@@ -28,10 +28,10 @@ func main() {
 	param := cmpb.DefaultParam()
 	param.PreBarWidth = 13
 	p := cmpb.NewWithParam(param)
+	p.SetPreBarFactory(calcStepsDur)
 
 	for _, key := range keys {
 		b := p.NewBar(key, total)
-		b.SetPreBar(calcStepsDur())
 
 		go func() {
 			for i := 0; i < total; i++ {
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/nu11ptr/cmpb"
+)
+
+const total = 100
+
+var keys = []string{"server1000", "server1001", "server1002"}
+
+func main() {
+	param := cmpb.DefaultParam()
+	param.Smooth = true
+	p := cmpb.NewWithParam(param)
+
+	for _, key := range keys {
+		b := p.NewBar(key, total)
+		go func() {
+			for i := 0; i < total; i++ {
+				time.Sleep(time.Duration(rand.Intn(250)) * time.Millisecond)
+				b.Increment()
+			}
+		}()
+	}
+
+	colors := cmpb.DefaultColors()
+	colors.Fill = cmpb.RedYellowGreen()
+	p.SetColors(colors)
+	p.Start()
+	p.Wait()
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/nu11ptr/cmpb"
+)
+
+const total = 100
+
+func main() {
+	p := cmpb.New()
+
+	for i, anim := range []cmpb.Animation{cmpb.Spinner, cmpb.Bounce} {
+		b := p.NewIndeterminateBar([]string{"spinner", "bounce"}[i], anim)
+		go func() {
+			for i := 0; i < total; i++ {
+				time.Sleep(time.Duration(rand.Intn(250)) * time.Millisecond)
+				// Halfway through we find out how much work there is
+				if i == total/2 {
+					b.SetTotal(total)
+				}
+				b.Increment()
+			}
+		}()
+	}
+
+	p.Start()
+	p.Wait()
+}
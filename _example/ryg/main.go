@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"math/rand"
 	"time"
 
@@ -22,6 +23,7 @@ func main() {
 
 	colors := new(cmpb.BarColors)
 	colors.SetAll(color.HiYellowString)
+	colors.Succeeded, colors.Failed = color.HiGreenString, color.HiRedString
 
 	for _, key := range keys {
 		b := p.NewBar(key, total)
@@ -30,23 +32,14 @@ func main() {
 				time.Sleep(time.Duration(rand.Intn(250)) * time.Millisecond)
 
 				if rand.Intn(total*3) == 1 {
-					colors.SetAll(color.HiRedString)
-					b.SetColors(colors)
-					b.Stop("error!", "A massive error occurred:\n    The error is catastrophic and cannot be recovered from")
-					break
-				} else {
-					if i == total-1 {
-						colors.SetAll(color.HiGreenString)
-						b.SetColors(colors)
-						b.SetMessage("completed")
-					} else {
-						action := actions[rand.Intn(len(actions))]
-						b.SetMessage(action)
-					}
+					b.Fail(errors.New("A massive error occurred:\n    The error is catastrophic and cannot be recovered from"))
+					return
 				}
-
+				action := actions[rand.Intn(len(actions))]
+				b.SetMessage(action)
 				b.Increment()
 			}
+			b.Succeed()
 		}()
 	}
 
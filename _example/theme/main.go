@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nu11ptr/cmpb"
+)
+
+const total = 100
+
+var (
+	keys    = []string{"server1000", "server1001", "server1002"}
+	actions = []string{"downloading...", "compiling source...", "fetching...", "committing work..."}
+)
+
+func main() {
+	name := flag.String("theme", "demo", "theme to use: "+strings.Join(cmpb.Themes(), ", "))
+	file := flag.String("file", "", "JSON file to load the theme from instead")
+	flag.Parse()
+
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		theme, err := cmpb.LoadTheme(f)
+		f.Close()
+		if err == nil {
+			err = cmpb.RegisterTheme(*file, theme)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		*name = *file
+	}
+
+	p := cmpb.New()
+	if err := p.SetTheme(*name); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	for _, key := range keys {
+		b := p.NewBar(key, total)
+		go func() {
+			for i := 0; i < total; i++ {
+				time.Sleep(time.Duration(rand.Intn(250)) * time.Millisecond)
+				action := actions[rand.Intn(len(actions))]
+				b.SetMessage(action)
+				b.Increment()
+			}
+		}()
+	}
+
+	p.Start()
+	p.Wait()
+}
@@ -2,7 +2,9 @@ package cmpb
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"math"
 	"strings"
 	"sync"
 	"time"
@@ -14,12 +16,20 @@ import (
 type BarColors struct {
 	Post, Key, KeyDiv, Msg, PreBar, LBracket, Empty, Full, Curr, RBracket,
 	PostBar, StopMsg, StopExtMsg func(string, ...interface{}) string
+	// Succeeded, Failed, Skipped and Cancelled (if set) color the message, extended message and bar
+	// of a bar that finished in that state
+	Succeeded, Failed, Skipped, Cancelled func(string, ...interface{}) string
+	// Fill (if set) colors the filled part of a running bar according to how complete it is,
+	// instead of Full and Curr
+	Fill FillColor
 }
 
-// DefaultColors returns a set of default colors for rendering the bar
+// DefaultColors returns a set of default colors for rendering the bar. No state colors are set, so
+// finished bars keep the colors of their fill
 func DefaultColors() *BarColors {
 	colors := new(BarColors)
 	colors.SetAll(noOp)
+	colors.Succeeded, colors.Failed, colors.Skipped, colors.Cancelled = nil, nil, nil, nil
 	return colors
 }
 
@@ -27,39 +37,73 @@ func DefaultColors() *BarColors {
 func (b *BarColors) SetAll(f func(string, ...interface{}) string) {
 	b.Post, b.Key, b.KeyDiv, b.Msg, b.PreBar, b.LBracket, b.Empty, b.Full, b.Curr, b.RBracket,
 		b.PostBar, b.StopMsg, b.StopExtMsg = f, f, f, f, f, f, f, f, f, f, f, f, f
+	b.Succeeded, b.Failed, b.Skipped, b.Cancelled = f, f, f, f
+	b.Fill = nil
 }
 
 func noOp(s string, _ ...interface{}) string { return s }
 
+// Animation represents how an indeterminate bar (one without a known total) is drawn
+type Animation int
+
+const (
+	// Spinner draws a single rotating glyph in the middle of the bar
+	Spinner Animation = iota
+	// Bounce draws a segment that moves back and forth between the brackets
+	Bounce
+)
+
 // Bar represents a single progress bar
 type Bar struct {
 	key, msg, extMsg    string
-	curr, total         int
+	curr, total         int64
 	lastRender, stopped bool
-	start               time.Time
-	preBarF, postBarF   func(int, int, time.Time, bool) string
+	openTotal, hidden   bool
+	start, changed      time.Time
+	finished            time.Time
+	preBarF, postBarF   func(int64, int64, time.Time, bool) string
 	colors              BarColors
+	anim                Animation
+	frame               int
+	doneCh              chan struct{}
+	state               State
+	err                 error
+
+	// parent and depth never change once the bar has been added to a progress
+	parent   *Bar
+	depth    int
+	children []*Bar
+	weight   float64
+
+	// style (if set) overrides the style of the progress
+	style BarStyle
+
+	// notify (if set) is called whenever the bar changes
+	notify func()
 
 	p   *Param
 	mut sync.Mutex
 }
 
-func newBar(key string, total int, p *Param) *Bar {
+func newBar(key string, total int64, p *Param) *Bar {
+	now := p.Clock.Now()
 	return &Bar{
-		key: key, msg: "", total: total, start: time.Now(), preBarF: CalcDur(), postBarF: CalcPct,
-		colors: *DefaultColors(), p: p,
+		key: key, msg: "", total: total, start: now, changed: now, preBarF: CalcDur(p.Clock),
+		postBarF: CalcPct, colors: *DefaultColors(), doneCh: make(chan struct{}), p: p,
 	}
 }
 
-// CalcDur calculates the duration since start time and returns a string
-func CalcDur() func(int, int, time.Time, bool) string {
+// CalcDur calculates the duration since start time (getting the current time from the given clock)
+// and returns a string. It is also a DecoratorFactory
+func CalcDur(clock Clock) func(int64, int64, time.Time, bool) string {
 	var final time.Time
 
-	return func(curr, total int, start time.Time, stopped bool) string {
-		last := time.Now()
+	return func(curr, total int64, start time.Time, stopped bool) string {
+		last := clock.Now()
 
-		// Record the time a single time on the last update so it doesn't keep updating after bar is done
-		if curr == total || stopped {
+		// Record the time a single time once the bar is done so it doesn't keep updating. Reaching the
+		// total isn't enough since it may still be open
+		if stopped {
 			if final.IsZero() {
 				final = last
 			}
@@ -71,18 +115,41 @@ func CalcDur() func(int, int, time.Time, bool) string {
 }
 
 // CalcSteps calculates the steps completed so far and returns a string
-func CalcSteps(curr, total int, start time.Time, stopped bool) string {
+func CalcSteps(curr, total int64, start time.Time, stopped bool) string {
+	if total <= 0 {
+		return fmt.Sprintf("(%d/?)", curr)
+	}
 	return fmt.Sprintf("(%d/%d)", curr, total)
 }
 
-// CalcPct calculates the percentage of work complete and returns as a string
-func CalcPct(curr, total int, start time.Time, stopped bool) string {
-	pct := (curr * 100) / total
-	return fmt.Sprintf("%d%%", pct)
+// CalcPct calculates the percentage of work complete and returns as a string. If the total is
+// not yet known, the running count is returned instead
+func CalcPct(curr, total int64, start time.Time, stopped bool) string {
+	if total <= 0 {
+		return fmt.Sprintf("%d", curr)
+	}
+	return fmt.Sprintf("%d%%", scale(curr, total, 100))
+}
+
+// scale returns curr/total expressed in units of n (curr*n/total) without overflowing when curr
+// is very large
+func scale(curr, total, n int64) int64 {
+	if n == 0 {
+		return 0
+	}
+	if curr <= math.MaxInt64/n && curr >= math.MinInt64/n {
+		return curr * n / total
+	}
+	return int64(float64(curr) / float64(total) * float64(n))
 }
 
+// DecoratorFactory creates a new decorator each time it is called, for decorators (such as CalcETA)
+// that keep track of the history of a single bar. The decorator gets the current time from the
+// given clock, which is the clock of the progress when set via SetPreBarFactory or SetPostBarFactory
+type DecoratorFactory func(clock Clock) func(int64, int64, time.Time, bool) string
+
 // SetPreBar sets the prebar function decorator
-func (b *Bar) SetPreBar(f func(int, int, time.Time, bool) string) {
+func (b *Bar) SetPreBar(f func(int64, int64, time.Time, bool) string) {
 	b.mut.Lock()
 	defer b.mut.Unlock()
 
@@ -90,29 +157,55 @@ func (b *Bar) SetPreBar(f func(int, int, time.Time, bool) string) {
 }
 
 // SetPostBar sets the postbar function decorator
-func (b *Bar) SetPostBar(f func(int, int, time.Time, bool) string) {
+func (b *Bar) SetPostBar(f func(int64, int64, time.Time, bool) string) {
 	b.mut.Lock()
 	defer b.mut.Unlock()
 
 	b.postBarF = f
 }
 
-func (b *Bar) update(curr int) {
-	if b.curr < b.total && !b.stopped {
-		if curr <= b.total {
-			b.curr = curr
-		} else {
-			b.curr = b.total
-		}
-		if b.curr == b.total {
-			b.lastRender = true
-			b.stopped = true
-		}
+func (b *Bar) update(curr int64) {
+	if b.stopped {
+		return
+	}
+	if curr < 0 {
+		curr = 0
+	}
+	// Without a total (or with one that is still growing) there is nothing to clamp to - the bar
+	// only finishes via Stop or once a final total is set
+	closed := b.total > 0 && !b.openTotal
+	if closed && curr > b.total {
+		curr = b.total
+	}
+	if curr != b.curr {
+		b.curr = curr
+		b.touch()
+	}
+	if closed && b.curr == b.total {
+		b.finish(StateSucceeded)
+	}
+}
+
+// touch records that the bar has changed
+func (b *Bar) touch() {
+	b.changed = b.p.Clock.Now()
+	if b.notify != nil {
+		b.notify()
 	}
 }
 
+// finish marks the bar as stopped in the given state so it renders one final time
+func (b *Bar) finish(state State) {
+	b.stopped = true
+	b.state = state
+	b.lastRender = true
+	b.touch()
+	b.finished = b.changed
+	close(b.doneCh)
+}
+
 // Update updates the current status of the bar
-func (b *Bar) Update(curr int) {
+func (b *Bar) Update(curr int64) {
 	b.mut.Lock()
 	defer b.mut.Unlock()
 
@@ -127,25 +220,90 @@ func (b *Bar) Increment() {
 	b.update(b.curr + 1)
 }
 
-// Stop stops the updating of the bar and sets a final msg (if not ab empty string)
+// Add updates the current status of the bar by delta. A negative delta rolls the status back
+// (e.g. for a retry), though never below zero
+func (b *Bar) Add(delta int64) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.update(b.curr + delta)
+}
+
+// SetTotal sets the total of the bar. Setting a total on an indeterminate bar converts it into
+// a regular bar, while a total of zero or less makes the bar indeterminate. If the new total is
+// at or below the current status, the current status is lowered to match and the bar completes,
+// unless the total has been marked as open via SetOpenTotal
+func (b *Bar) SetTotal(total int64) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if b.stopped {
+		return
+	}
+	if total != b.total {
+		b.total = total
+		b.touch()
+	}
+	b.update(b.curr)
+}
+
+// AddTotal adds delta (which may be negative) to the total of the bar. It otherwise behaves
+// exactly like SetTotal
+func (b *Bar) AddTotal(delta int64) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if b.stopped {
+		return
+	}
+	if delta != 0 {
+		b.total += delta
+		b.touch()
+	}
+	b.update(b.curr)
+}
+
+// SetOpenTotal marks the total as still growing (or not). While open, reaching the total does not
+// complete the bar and the current status may temporarily exceed the total. Closing the total
+// completes the bar if the current status has already reached it
+func (b *Bar) SetOpenTotal(open bool) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if b.stopped {
+		return
+	}
+	b.openTotal = open
+	b.update(b.curr)
+}
+
+// SetAnimation sets how the bar is drawn while its total is unknown
+func (b *Bar) SetAnimation(anim Animation) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.anim = anim
+}
+
+// Stop stops the updating of the bar and sets a final msg (if not ab empty string). The bar is left
+// in StateStopped - use Succeed, Fail, Skip or Cancel to give it a specific outcome instead
 func (b *Bar) Stop(msg, extMsg string) {
 	b.mut.Lock()
 	defer b.mut.Unlock()
 
-	if !b.stopped {
-		b.stopped = true
-		b.lastRender = true
-		if msg != "" {
-			b.msg = b.colors.StopMsg(msg)
-		}
-		if extMsg != "" {
-			b.extMsg = b.colors.StopExtMsg(extMsg)
-			// Only prepend the key name when rendered below the bars
-			if !b.p.InlineExtMsg {
-				b.extMsg = b.colors.Key(b.key) + b.colors.KeyDiv(": ") + b.extMsg
-			}
+	b.end(StateStopped, msg, extMsg)
+}
+
+// BindContext binds the bar to ctx so that if ctx is done before the bar finishes, the bar is
+// cancelled
+func (b *Bar) BindContext(ctx context.Context) {
+	go func() {
+		select {
+		case <-ctx.Done():
+			b.Cancel()
+		case <-b.doneCh:
 		}
-	}
+	}()
 }
 
 func (b *Bar) extendedMsg() string {
@@ -176,7 +334,10 @@ func (b *Bar) SetMessage(msg string) {
 	b.mut.Lock()
 	defer b.mut.Unlock()
 
-	b.msg = msg
+	if msg != b.msg {
+		b.msg = msg
+		b.touch()
+	}
 }
 
 func (b *Bar) isLastRender() bool {
@@ -191,46 +352,131 @@ func (b *Bar) isLastRender() bool {
 	return lr
 }
 
-func (b *Bar) makeBar(c *BarColors, param *Param, buf *bytes.Buffer) {
-	buf.WriteString(c.LBracket(string(param.LBracket)))
+func (b *Bar) makeAnimation(c *BarColors, style BarStyle, param *Param, width int,
+	buf *bytes.Buffer) {
+	// The filled and empty cells of the style are reused to draw the animation
+	fill := func(n int) string {
+		full, _, _ := style.Fill(1, 1, n)
+		return full
+	}
+	clear := func(n int) string {
+		_, _, empty := style.Fill(0, 1, n)
+		return empty
+	}
 
-	full := b.curr * (param.BarWidth - 2) / b.total
-	empty := (param.BarWidth - 2) - full
-	if full > 0 {
-		if empty > 0 {
-			full--
+	switch b.anim {
+	case Bounce:
+		seg := param.BounceWidth
+		if seg > width {
+			seg = width
+		}
+		// The segment moves right until it hits the bracket, then back left again
+		pos, travel := 0, width-seg
+		if travel > 0 {
+			pos = b.frame % (2 * travel)
+			if pos > travel {
+				pos = 2*travel - pos
+			}
+		}
+		buf.WriteString(c.Empty(clear(pos)))
+		buf.WriteString(c.Full(fill(seg)))
+		buf.WriteString(c.Empty(clear(travel - pos)))
+	default:
+		glyphs := []rune(param.Spinner)
+		if width <= 0 || len(glyphs) == 0 {
+			buf.WriteString(c.Empty(clear(width)))
+			return
 		}
-		buf.WriteString(c.Full(strings.Repeat(string(param.Full), full)))
-		if empty > 0 {
-			buf.WriteString(c.Curr(string(param.Curr)))
+		left := (width - 1) / 2
+		buf.WriteString(c.Empty(clear(left)))
+		buf.WriteString(c.Curr(string(glyphs[b.frame%len(glyphs)])))
+		buf.WriteString(c.Empty(clear(width - 1 - left)))
+	}
+}
+
+func (b *Bar) makeBar(c *BarColors, param *Param, barWidth int, buf *bytes.Buffer) {
+	style := b.style
+	if style == nil {
+		style = param.Style
+		if style == nil {
+			style = param.style()
 		}
 	}
-	buf.WriteString(c.Empty(strings.Repeat(string(param.Empty), empty)))
+	left, right := style.Brackets()
+	buf.WriteString(c.LBracket(left))
+	defer buf.WriteString(c.RBracket(right))
 
-	buf.WriteString(c.RBracket(string(param.RBracket)))
+	width := barWidth - strutil.Len(left) - strutil.Len(right)
+	if width < 0 {
+		width = 0
+	}
+	// A successful bar is always drawn full, even without a total
+	if b.state == StateSucceeded {
+		full, _, _ := style.Fill(1, 1, width)
+		buf.WriteString(c.Full(full))
+		return
+	}
+	if b.total <= 0 {
+		b.makeAnimation(c, style, param, width, buf)
+		// Freeze the animation once the bar is stopped
+		if !b.stopped {
+			b.frame++
+		}
+		return
+	}
+
+	full, head, empty := style.Fill(b.curr, b.total, width)
+	if full != "" {
+		buf.WriteString(c.Full(full))
+	}
+	if head != "" {
+		buf.WriteString(c.Curr(head))
+	}
+	buf.WriteString(c.Empty(empty))
 }
 
 func (b *Bar) String() string {
+	return b.render(b.p.layout(0))
+}
+
+func (b *Bar) render(l layout) (line string) {
 	b.mut.Lock()
 	defer b.mut.Unlock()
 
 	buf := new(bytes.Buffer)
 	param := b.p
-	w := param.PrePad + param.KeyWidth + param.MsgWidth + param.PreBarWidth +
-		param.BarWidth + param.PostBarWidth + 5 // spaces + keyDiv
-	buf.Grow(w)
+	// A bad decorator or color shouldn't take down the render goroutine, so show the problem instead
+	defer func() {
+		if r := recover(); r != nil {
+			line = fmt.Sprintf("%s%s%c render failed: %v", strings.Repeat(" ", param.PrePad), b.key,
+				param.KeyDiv, r)
+		}
+	}()
+	buf.Grow(l.width)
 	c := &b.colors
+	// Finished bars are drawn in the color of their state
+	if sc := c.stateColor(b.state); sc != nil {
+		colors := *c
+		colors.Full, colors.Curr = sc, sc
+		c = &colors
+	} else if c.Fill != nil {
+		info := barInfo{curr: b.curr, total: b.total, state: b.state}
+		fill := c.Fill(info.fraction(), param.ColorDepth)
+		colors := *c
+		colors.Full, colors.Curr = fill, fill
+		c = &colors
+	}
 
 	buf.WriteString(strings.Repeat(" ", param.PrePad))
-	buf.WriteString(strutil.ResizeR(c.Key(b.key), c.Post(param.Post), param.KeyWidth))
+	buf.WriteString(strutil.ResizeR(b.indent()+c.Key(b.key), c.Post(param.Post), param.KeyWidth))
 	buf.WriteString(c.KeyDiv(string(param.KeyDiv)))
 	buf.WriteRune(' ')
-	buf.WriteString(strutil.ResizeR(c.Msg(b.msg), c.Post(param.Post), param.MsgWidth))
+	buf.WriteString(strutil.ResizeR(c.Msg(b.msg), c.Post(param.Post), l.msgWidth))
 	buf.WriteRune(' ')
 	preBar := c.PreBar(b.preBarF(b.curr, b.total, b.start, b.stopped))
 	buf.WriteString(strutil.ResizeL(preBar, c.Post(param.Post), param.PreBarWidth))
 	buf.WriteRune(' ')
-	b.makeBar(c, param, buf)
+	b.makeBar(c, param, l.barWidth, buf)
 	buf.WriteRune(' ')
 	postBar := c.PostBar(b.postBarF(b.curr, b.total, b.start, b.stopped))
 	buf.WriteString(strutil.ResizeL(postBar, c.Post(param.Post), param.PostBarWidth))
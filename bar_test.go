@@ -1,21 +1,26 @@
 package cmpb_test
 
 import (
+	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/nu11ptr/cmpb"
+	"github.com/nu11ptr/cmpb/cmpbtest"
 )
 
 func TestCalcPct(t *testing.T) {
 	tests := []struct {
 		name        string
-		curr, total int
+		curr, total int64
 		output      string
 	}{
 		{"0", 0, 10, "0%"},
 		{"Round", 33, 99, "33%"},
 		{"100", 10, 10, "100%"},
+		{"Huge", 1 << 61, 1 << 62, "50%"},
+		{"Unknown", 42, 0, "42"},
 	}
 
 	for _, test := range tests {
@@ -28,6 +33,45 @@ func TestCalcPct(t *testing.T) {
 	}
 }
 
+func TestElapsed(t *testing.T) {
+	clock := cmpbtest.NewClock(time.Now())
+	param := cmpb.DefaultParam()
+	param.Clock = clock
+	p := cmpb.NewWithParam(param)
+
+	t.Run("Closed", func(t *testing.T) {
+		b := p.NewBar("closed", 10)
+		clock.Advance(5 * time.Second)
+		b.Update(10)
+		_ = b.String()
+		clock.Advance(time.Minute)
+		expected := "closed    :                               5s [====================] 100%"
+		output := b.String()
+		if output != expected {
+			t.Error("want", expected, "got", output)
+		}
+	})
+	t.Run("Open", func(t *testing.T) {
+		b := p.NewBar("open", 10)
+		b.SetOpenTotal(true)
+		clock.Advance(5 * time.Second)
+		// Reaching the total while it is still open doesn't stop the timer
+		b.Update(10)
+		_ = b.String()
+		clock.Advance(time.Minute)
+		b.AddTotal(10)
+		b.Update(20)
+		b.SetOpenTotal(false)
+		_ = b.String()
+		clock.Advance(time.Minute)
+		expected := "open      :                            1m 5s [====================] 100%"
+		output := b.String()
+		if output != expected {
+			t.Error("want", expected, "got", output)
+		}
+	})
+}
+
 func TestSteps(t *testing.T) {
 	output := cmpb.CalcSteps(10, 33, time.Now(), false)
 	if output != "(10/33)" {
@@ -66,3 +110,155 @@ func TestString(t *testing.T) {
 		}
 	})
 }
+
+func TestIndeterminate(t *testing.T) {
+	p := cmpb.New()
+
+	t.Run("Spinner", func(t *testing.T) {
+		b := p.NewIndeterminateBar("spin", cmpb.Spinner)
+		b.Update(42)
+		for _, glyph := range []string{"|", "/", "-", "\\", "|"} {
+			expected := "spin      :                               0s [---------" + glyph +
+				"----------]   42"
+			output := b.String()
+			if output != expected {
+				t.Error("want", expected, "got", output)
+			}
+		}
+	})
+	t.Run("Bounce", func(t *testing.T) {
+		b := p.NewIndeterminateBar("bounce", cmpb.Bounce)
+		// Move past the right bracket so it is on its way back
+		for i := 0; i < 18; i++ {
+			_ = b.String()
+		}
+		expected := "bounce    :                               0s [--------------====--]    0"
+		output := b.String()
+		if output != expected {
+			t.Error("want", expected, "got", output)
+		}
+	})
+	t.Run("SetTotal", func(t *testing.T) {
+		b := p.NewIndeterminateBar("total", cmpb.Spinner)
+		b.Update(5)
+		b.SetTotal(10)
+		expected := "total     :                               0s [=========>----------]  50%"
+		output := b.String()
+		if output != expected {
+			t.Error("want", expected, "got", output)
+		}
+	})
+}
+
+func TestDynamicTotal(t *testing.T) {
+	p := cmpb.New()
+
+	t.Run("Open", func(t *testing.T) {
+		b := p.NewBar("open", 10)
+		b.SetOpenTotal(true)
+		b.Update(12)
+		expected := "open      :                               0s [====================] 120%"
+		output := b.String()
+		if output != expected {
+			t.Error("want", expected, "got", output)
+		}
+		b.AddTotal(10)
+		expected = "open      :                               0s [===========>--------]  60%"
+		output = b.String()
+		if output != expected {
+			t.Error("want", expected, "got", output)
+		}
+		b.SetOpenTotal(false)
+		b.Update(20)
+		b.Increment()
+		expected = "open      :                               0s [====================] 100%"
+		output = b.String()
+		if output != expected {
+			t.Error("want", expected, "got", output)
+		}
+	})
+	t.Run("BelowCurr", func(t *testing.T) {
+		b := p.NewBar("below", 10)
+		b.Update(8)
+		b.SetTotal(5)
+		b.AddTotal(5)
+		expected := "below     :                               0s [====================] 100%"
+		output := b.String()
+		if output != expected {
+			t.Error("want", expected, "got", output)
+		}
+	})
+}
+
+func TestAdd(t *testing.T) {
+	p := cmpb.New()
+	b := p.NewBar("add", 10<<30)
+
+	b.Add(6 << 30)
+	b.Add(-1 << 30)
+	expected := "add       :                               0s [=========>----------]  50%"
+	output := b.String()
+	if output != expected {
+		t.Error("want", expected, "got", output)
+	}
+	b.Add(-10 << 30)
+	expected = "add       :                               0s [--------------------]   0%"
+	output = b.String()
+	if output != expected {
+		t.Error("want", expected, "got", output)
+	}
+}
+
+// setenv sets (or unsets, if empty) environment variables for the rest of a test, returning a
+// function to restore them
+func setenv(vars map[string]string) (restore func()) {
+	old := make(map[string]*string, len(vars))
+	for name, value := range vars {
+		if prev, ok := os.LookupEnv(name); ok {
+			old[name] = &prev
+		} else {
+			old[name] = nil
+		}
+		if value == "" {
+			os.Unsetenv(name)
+		} else {
+			os.Setenv(name, value)
+		}
+	}
+	return func() {
+		for name, prev := range old {
+			if prev == nil {
+				os.Unsetenv(name)
+			} else {
+				os.Setenv(name, *prev)
+			}
+		}
+	}
+}
+
+func TestSmooth(t *testing.T) {
+	tests := []struct {
+		name, locale string
+		curr         int64
+		output       string
+	}{
+		{"Empty", "en_US.UTF-8", 0, "[          ]"},
+		{"Eighth", "en_US.UTF-8", 1, "[▏         ]"},
+		{"Partial", "en_US.utf8", 28, "[███▌      ]"},
+		{"Full", "en_US.UTF-8", 80, "[██████████]"},
+		{"Fallback", "C", 28, "[==>-------]"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer setenv(map[string]string{"TERM": "xterm", "LC_ALL": test.locale})()
+			param := cmpb.DefaultParam()
+			param.Smooth, param.BarWidth = true, 12
+			b := cmpb.NewWithParam(param).NewBar("smooth", 80)
+			b.Update(test.curr)
+			if output := b.String(); !strings.Contains(output, test.output) {
+				t.Error("want", test.output, "got", output)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package cmpb
+
+import "time"
+
+// Clock provides the current time and timers to the bars, decorators and render loop, so time can
+// be controlled in tests (see the cmpbtest package)
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// After returns a channel that receives the current time once d has passed
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the clock backed by the time package
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
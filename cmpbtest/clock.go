@@ -0,0 +1,95 @@
+// Package cmpbtest provides helpers for testing code that renders progress bars
+package cmpbtest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nu11ptr/cmpb"
+)
+
+var _ cmpb.Clock = (*Clock)(nil)
+
+// Clock is a fake cmpb.Clock whose time only moves when advanced. Set it as the Clock of a
+// cmpb.Param to control time and when frames are rendered
+type Clock struct {
+	mut    sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*timer
+}
+
+type timer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewClock creates a new fake clock set to the given time
+func NewClock(now time.Time) *Clock {
+	c := &Clock{now: now}
+	c.cond = sync.NewCond(&c.mut)
+	return c
+}
+
+// Now returns the current time of the clock
+func (c *Clock) Now() time.Time {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	return c.now
+}
+
+// After returns a channel that receives the time once the clock has been advanced by at least d
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.timers = append(c.timers, &timer{at: c.now.Add(d), ch: ch})
+	c.cond.Broadcast()
+	return ch
+}
+
+// Advance moves the clock forward by d, firing any timers that are now due in the order they were
+// due
+func (c *Clock) Advance(d time.Duration) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.now = c.now.Add(d)
+	sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].at.Before(c.timers[j].at) })
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.ch <- t.at
+	}
+	c.timers = pending
+}
+
+// BlockUntil blocks until at least n timers are waiting to fire. A render loop waits on a timer
+// between frames, so this can be used to wait until it has finished rendering a frame
+func (c *Clock) BlockUntil(n int) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+// Frame advances the clock by d and then waits until the render loop (using RefreshInterval) is
+// waiting for its next frame, so everything rendered as a result is complete once it returns. It
+// must only be used while the render loop is running and not about to end
+func (c *Clock) Frame(d time.Duration) {
+	c.BlockUntil(1)
+	c.Advance(d)
+	c.BlockUntil(1)
+}
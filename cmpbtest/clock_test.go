@@ -0,0 +1,59 @@
+package cmpbtest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nu11ptr/cmpb/cmpbtest"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := cmpbtest.NewClock(start)
+	late := c.After(2 * time.Second)
+	early := c.After(time.Second)
+
+	select {
+	case <-c.After(0):
+	default:
+		t.Error("zero timer did not fire straight away")
+	}
+
+	c.Advance(500 * time.Millisecond)
+	select {
+	case <-early:
+		t.Fatal("timer fired early")
+	default:
+	}
+
+	c.Advance(time.Second)
+	if at := <-early; !at.Equal(start.Add(time.Second)) {
+		t.Error("want", start.Add(time.Second), "got", at)
+	}
+	select {
+	case <-late:
+		t.Fatal("timer fired early")
+	default:
+	}
+	if now := c.Now(); !now.Equal(start.Add(1500 * time.Millisecond)) {
+		t.Error("want", start.Add(1500*time.Millisecond), "got", now)
+	}
+}
+
+func TestBlockUntil(t *testing.T) {
+	c := cmpbtest.NewClock(time.Now())
+	done := make(chan struct{})
+	go func() {
+		c.BlockUntil(2)
+		close(done)
+	}()
+
+	c.After(time.Second)
+	select {
+	case <-done:
+		t.Fatal("returned with only one timer waiting")
+	case <-time.After(10 * time.Millisecond):
+	}
+	c.After(time.Second)
+	<-done
+}
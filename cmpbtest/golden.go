@@ -0,0 +1,43 @@
+package cmpbtest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// UpdateEnv is the environment variable that, when set to a non-empty value, makes Golden write
+// the golden files instead of comparing against them
+const UpdateEnv = "CMPBTEST_UPDATE"
+
+// Golden compares got with the contents of the golden file at path (such as
+// "testdata/basic.golden"), failing the test if they differ. Run the tests with CMPBTEST_UPDATE=1
+// to create or update the golden files from the current output
+func Golden(t testing.TB, path, got string) {
+	t.Helper()
+
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal("creating golden file directory:", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal("writing golden file:", err)
+		}
+		return
+	}
+
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run with %s=1 to create it): %v", UpdateEnv, err)
+	}
+	if string(want) != got {
+		t.Errorf("%s differs from golden file\nwant:\n%s\ngot:\n%s", path, want, got)
+	}
+}
+
+// GoldenScreen compares the visible screen of the terminal with the golden file at path
+func GoldenScreen(t testing.TB, path string, term *Terminal) {
+	t.Helper()
+	Golden(t, path, term.String()+"\n")
+}
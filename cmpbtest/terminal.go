@@ -0,0 +1,259 @@
+package cmpbtest
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+type parseState int
+
+const (
+	ground parseState = iota
+	escape
+	csi
+)
+
+// Terminal is an in-memory VT100 terminal emulator. Writing to it interprets the escape sequences
+// used by cmpb (cursor movement, erasing and colors) so the visible screen can be inspected. Since
+// a real terminal normally translates a line feed into a carriage return plus line feed, so does
+// Terminal. Colors and other character attributes are ignored and every rune takes a single column
+type Terminal struct {
+	mut           sync.Mutex
+	width, height int
+	grid          [][]rune
+	scrollback    []string
+	row, col      int
+	// wrap is set once a rune is written to the last column, so the next rune wraps to a new line
+	wrap    bool
+	state   parseState
+	params  []byte
+	partial []byte
+}
+
+// NewTerminal creates a new blank terminal with the given number of columns and rows
+func NewTerminal(width, height int) *Terminal {
+	t := &Terminal{width: width, height: height, grid: make([][]rune, height)}
+	for i := range t.grid {
+		t.grid[i] = t.blankRow()
+	}
+	return t
+}
+
+func (t *Terminal) blankRow() []rune {
+	row := make([]rune, t.width)
+	for i := range row {
+		row[i] = ' '
+	}
+	return row
+}
+
+// Write interprets p as output to the terminal. Escape sequences and runes may be split across
+// writes
+func (t *Terminal) Write(p []byte) (int, error) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	buf := append(t.partial, p...)
+	for len(buf) > 0 {
+		if !utf8.FullRune(buf) {
+			break
+		}
+		r, size := utf8.DecodeRune(buf)
+		buf = buf[size:]
+		t.handle(r)
+	}
+	t.partial = append([]byte(nil), buf...)
+	return len(p), nil
+}
+
+func (t *Terminal) handle(r rune) {
+	switch t.state {
+	case escape:
+		if r == '[' {
+			t.state, t.params = csi, t.params[:0]
+		} else {
+			// Anything other than a control sequence is unsupported and ignored
+			t.state = ground
+		}
+	case csi:
+		if r >= '@' && r <= '~' {
+			t.state = ground
+			t.control(r, t.args())
+		} else {
+			t.params = append(t.params, byte(r))
+		}
+	default:
+		t.print(r)
+	}
+}
+
+// args parses the numeric parameters of a control sequence (missing parameters are -1)
+func (t *Terminal) args() []int {
+	var args []int
+	for _, s := range strings.Split(string(t.params), ";") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			n = -1
+		}
+		args = append(args, n)
+	}
+	return args
+}
+
+// arg returns the ith parameter or def if it is missing or zero
+func arg(args []int, i, def int) int {
+	if i >= len(args) || args[i] <= 0 {
+		return def
+	}
+	return args[i]
+}
+
+func (t *Terminal) control(final rune, args []int) {
+	t.wrap = false
+	switch final {
+	case 'A':
+		t.row = clamp(t.row-arg(args, 0, 1), 0, t.height-1)
+	case 'B':
+		t.row = clamp(t.row+arg(args, 0, 1), 0, t.height-1)
+	case 'C':
+		t.col = clamp(t.col+arg(args, 0, 1), 0, t.width-1)
+	case 'D':
+		t.col = clamp(t.col-arg(args, 0, 1), 0, t.width-1)
+	case 'G':
+		t.col = clamp(arg(args, 0, 1)-1, 0, t.width-1)
+	case 'H', 'f':
+		t.row = clamp(arg(args, 0, 1)-1, 0, t.height-1)
+		t.col = clamp(arg(args, 1, 1)-1, 0, t.width-1)
+	case 'J':
+		t.eraseDisplay(arg(args, 0, 0))
+	case 'K':
+		t.eraseLine(t.row, arg(args, 0, 0))
+	}
+	// Anything else (such as colors) doesn't change what is on screen
+}
+
+func (t *Terminal) eraseDisplay(mode int) {
+	switch mode {
+	case 0:
+		t.eraseLine(t.row, 0)
+		for i := t.row + 1; i < t.height; i++ {
+			t.grid[i] = t.blankRow()
+		}
+	case 1:
+		t.eraseLine(t.row, 1)
+		for i := 0; i < t.row; i++ {
+			t.grid[i] = t.blankRow()
+		}
+	default:
+		for i := range t.grid {
+			t.grid[i] = t.blankRow()
+		}
+	}
+}
+
+func (t *Terminal) eraseLine(row, mode int) {
+	from, to := 0, t.width
+	switch mode {
+	case 0:
+		from = t.col
+	case 1:
+		to = t.col + 1
+	}
+	for i := from; i < to && i < t.width; i++ {
+		t.grid[row][i] = ' '
+	}
+}
+
+func (t *Terminal) print(r rune) {
+	switch r {
+	case '\x1b':
+		t.state = escape
+	case '\n':
+		t.lineFeed()
+	case '\r':
+		t.col, t.wrap = 0, false
+	case '\b':
+		if t.col > 0 {
+			t.col--
+		}
+		t.wrap = false
+	default:
+		if r < ' ' {
+			return
+		}
+		if t.wrap {
+			t.lineFeed()
+		}
+		t.grid[t.row][t.col] = r
+		if t.col < t.width-1 {
+			t.col++
+		} else {
+			t.wrap = true
+		}
+	}
+}
+
+// lineFeed moves to the start of the next line, scrolling the screen up at the bottom
+func (t *Terminal) lineFeed() {
+	t.col, t.wrap = 0, false
+	if t.row < t.height-1 {
+		t.row++
+		return
+	}
+	t.scrollback = append(t.scrollback, trimRow(t.grid[0]))
+	copy(t.grid, t.grid[1:])
+	t.grid[t.height-1] = t.blankRow()
+}
+
+func trimRow(row []rune) string {
+	return strings.TrimRight(string(row), " ")
+}
+
+func clamp(n, min, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// Screen returns each visible row of the terminal with trailing spaces removed
+func (t *Terminal) Screen() []string {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	rows := make([]string, t.height)
+	for i, row := range t.grid {
+		rows[i] = trimRow(row)
+	}
+	return rows
+}
+
+// Scrollback returns the rows that have scrolled off the top of the screen, oldest first
+func (t *Terminal) Scrollback() []string {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	return append([]string(nil), t.scrollback...)
+}
+
+// Cursor returns the current (zero based) row and column of the cursor
+func (t *Terminal) Cursor() (row, col int) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	return t.row, t.col
+}
+
+// String returns the visible screen as text, one line per row, without any trailing blank rows
+func (t *Terminal) String() string {
+	rows := t.Screen()
+	for len(rows) > 0 && rows[len(rows)-1] == "" {
+		rows = rows[:len(rows)-1]
+	}
+	return strings.Join(rows, "\n")
+}
@@ -0,0 +1,59 @@
+package cmpbtest_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nu11ptr/cmpb/cmpbtest"
+)
+
+func TestTerminal(t *testing.T) {
+	tests := []struct {
+		name, input string
+		screen      []string
+		row, col    int
+	}{
+		{"Text", "ab\ncd", []string{"ab", "cd", ""}, 1, 2},
+		{"Colors", "\x1b[1;31mred\x1b[0m", []string{"red", "", ""}, 0, 3},
+		{"ScrollUp", "one\ntwo\n\x1b[2Axx", []string{"xxe", "two", ""}, 0, 2},
+		{"CursorDown", "one\ntwo\n\x1b[2A\x1b[1Bxx", []string{"one", "xxo", ""}, 1, 2},
+		{"ClearDown", "one\ntwo\nsix\x1b[2A\x1b[J", []string{"one", "", ""}, 0, 3},
+		{"ClearLine", "abcd\x1b[2D\x1b[K", []string{"ab", "", ""}, 0, 2},
+		{"Wrap", "abcdefgh", []string{"abcde", "fgh", ""}, 1, 3},
+		{"NoEarlyWrap", "abcde\n", []string{"abcde", "", ""}, 1, 0},
+		{"Scroll", "1\n2\n3\n4", []string{"2", "3", "4"}, 2, 1},
+		{"Unicode", "✓ ok", []string{"✓ ok", "", ""}, 0, 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			term := cmpbtest.NewTerminal(5, 3)
+			term.Write([]byte(test.input))
+			if screen := term.Screen(); strings.Join(screen, "|") != strings.Join(test.screen, "|") {
+				t.Errorf("want %q got %q", test.screen, screen)
+			}
+			if row, col := term.Cursor(); row != test.row || col != test.col {
+				t.Error("want", test.row, test.col, "got", row, col)
+			}
+		})
+	}
+}
+
+func TestTerminalSplitWrites(t *testing.T) {
+	term := cmpbtest.NewTerminal(10, 2)
+	input := []byte("one\ntw✓\x1b[1Azz")
+	for i := range input {
+		term.Write(input[i : i+1])
+	}
+	if s := term.String(); s != "onezz\ntw✓" {
+		t.Errorf("want %q got %q", "onezz\ntw✓", s)
+	}
+}
+
+func TestScrollback(t *testing.T) {
+	term := cmpbtest.NewTerminal(5, 2)
+	term.Write([]byte("1\n2\n3\n4"))
+	if sb := term.Scrollback(); strings.Join(sb, ",") != "1,2" {
+		t.Error("want [1 2] got", sb)
+	}
+}
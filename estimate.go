@@ -0,0 +1,180 @@
+package cmpb
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/nu11ptr/cmpb/strutil"
+)
+
+// Estimator estimates the rate of progress of a bar from samples of its current status
+type Estimator interface {
+	// Sample records the current status of the bar at the given time
+	Sample(curr int64, at time.Time)
+	// Rate returns the estimated rate of progress in units per second (0 if not yet known)
+	Rate() float64
+}
+
+type sample struct {
+	curr int64
+	at   time.Time
+}
+
+func rateBetween(first, last sample) float64 {
+	secs := last.at.Sub(first.at).Seconds()
+	if secs <= 0 {
+		return 0
+	}
+	return float64(last.curr-first.curr) / secs
+}
+
+// AvgEstimator estimates the rate as the simple average over the entire life of the bar
+type AvgEstimator struct {
+	first, last sample
+	samples     int
+}
+
+// NewAvgEstimator creates a new simple average estimator
+func NewAvgEstimator() *AvgEstimator {
+	return new(AvgEstimator)
+}
+
+// Sample records the current status of the bar at the given time
+func (e *AvgEstimator) Sample(curr int64, at time.Time) {
+	if e.samples == 0 {
+		e.first = sample{curr, at}
+	}
+	e.last = sample{curr, at}
+	e.samples++
+}
+
+// Rate returns the estimated rate of progress in units per second
+func (e *AvgEstimator) Rate() float64 {
+	return rateBetween(e.first, e.last)
+}
+
+// EWMAEstimator estimates the rate using an exponentially weighted moving average of the rate
+// between each sample
+type EWMAEstimator struct {
+	alpha, rate float64
+	last        sample
+	samples     int
+}
+
+// NewEWMAEstimator creates a new exponentially weighted moving average estimator. Alpha is the
+// weight (0 < alpha <= 1) given to each new sample - the higher the value, the faster the estimate
+// reacts to changes in rate
+func NewEWMAEstimator(alpha float64) *EWMAEstimator {
+	return &EWMAEstimator{alpha: alpha}
+}
+
+// Sample records the current status of the bar at the given time
+func (e *EWMAEstimator) Sample(curr int64, at time.Time) {
+	next := sample{curr, at}
+	if e.samples > 0 {
+		// Ignore samples that arrive without time passing as they carry no rate information
+		if !at.After(e.last.at) {
+			return
+		}
+		rate := rateBetween(e.last, next)
+		if e.samples == 1 {
+			e.rate = rate
+		} else {
+			e.rate = e.alpha*rate + (1-e.alpha)*e.rate
+		}
+	}
+	e.last = next
+	e.samples++
+}
+
+// Rate returns the estimated rate of progress in units per second
+func (e *EWMAEstimator) Rate() float64 {
+	return e.rate
+}
+
+// WindowEstimator estimates the rate as the average over a sliding window of recent history
+type WindowEstimator struct {
+	window  time.Duration
+	samples []sample
+}
+
+// NewWindowEstimator creates a new sliding window estimator covering the given duration
+func NewWindowEstimator(window time.Duration) *WindowEstimator {
+	return &WindowEstimator{window: window}
+}
+
+// Sample records the current status of the bar at the given time
+func (e *WindowEstimator) Sample(curr int64, at time.Time) {
+	e.samples = append(e.samples, sample{curr, at})
+
+	// Keep a single sample from at or before the start of the window so it is always fully covered
+	cutoff := at.Add(-e.window)
+	drop := 0
+	for drop < len(e.samples)-2 && !e.samples[drop+1].at.After(cutoff) {
+		drop++
+	}
+	e.samples = e.samples[drop:]
+}
+
+// Rate returns the estimated rate of progress in units per second
+func (e *WindowEstimator) Rate() float64 {
+	if len(e.samples) < 2 {
+		return 0
+	}
+	return rateBetween(e.samples[0], e.samples[len(e.samples)-1])
+}
+
+const maxDurationSecs = float64(math.MaxInt64) / float64(time.Second)
+
+// sampler feeds an estimator from a decorator, which is called once per render
+type sampler struct {
+	est   Estimator
+	clock Clock
+}
+
+func (s *sampler) rate(curr int64, stopped bool) float64 {
+	// The first sample is the status at the first render, since a bar may not have started from zero
+	if !stopped {
+		s.est.Sample(curr, s.clock.Now())
+	}
+	return s.est.Rate()
+}
+
+// CalcETA estimates the time remaining and returns a string. Each decorator created by the factory
+// gets its own estimator from newEst, as the estimator tracks the history of a single bar
+func CalcETA(newEst func() Estimator) DecoratorFactory {
+	return func(clock Clock) func(int64, int64, time.Time, bool) string {
+		s := &sampler{est: newEst(), clock: clock}
+
+		return func(curr, total int64, start time.Time, stopped bool) string {
+			return fmtETA(curr, total, stopped, s.rate(curr, stopped))
+		}
+	}
+}
+
+func fmtETA(curr, total int64, stopped bool, rate float64) string {
+	if total > 0 && curr >= total {
+		return strutil.FmtDuration(0)
+	}
+	if total <= 0 || stopped || rate <= 0 {
+		return "?"
+	}
+	secs := float64(total-curr) / rate
+	if secs > maxDurationSecs {
+		return "?"
+	}
+	return strutil.FmtDuration(time.Duration(secs * float64(time.Second)))
+}
+
+// CalcRate calculates the rate of progress per second and returns a string. Like CalcETA, each
+// decorator created gets its own estimator from newEst
+func CalcRate(newEst func() Estimator) DecoratorFactory {
+	return func(clock Clock) func(int64, int64, time.Time, bool) string {
+		s := &sampler{est: newEst(), clock: clock}
+
+		return func(curr, total int64, start time.Time, stopped bool) string {
+			return fmt.Sprintf("%.1f/s", s.rate(curr, stopped))
+		}
+	}
+}
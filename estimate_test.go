@@ -0,0 +1,128 @@
+package cmpb_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nu11ptr/cmpb"
+	"github.com/nu11ptr/cmpb/cmpbtest"
+)
+
+func TestEstimators(t *testing.T) {
+	start := time.Now()
+	// 10/s for 10 seconds, then 1/s for the last 2 seconds
+	samples := []struct {
+		curr int64
+		secs int
+	}{
+		{0, 0}, {50, 5}, {100, 10}, {101, 11}, {102, 12},
+	}
+	tests := []struct {
+		name string
+		est  cmpb.Estimator
+		rate float64
+	}{
+		{"Avg", cmpb.NewAvgEstimator(), 8.5},
+		{"EWMA", cmpb.NewEWMAEstimator(0.5), 3.25},
+		{"Window", cmpb.NewWindowEstimator(2 * time.Second), 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if rate := test.est.Rate(); rate != 0 {
+				t.Error("want", 0, "got", rate)
+			}
+			for _, s := range samples {
+				test.est.Sample(s.curr, start.Add(time.Duration(s.secs)*time.Second))
+			}
+			if rate := test.est.Rate(); rate != test.rate {
+				t.Error("want", test.rate, "got", rate)
+			}
+		})
+	}
+}
+
+// newAvg creates a new estimator for each bar
+func newAvg() cmpb.Estimator { return cmpb.NewAvgEstimator() }
+
+func TestCalcETA(t *testing.T) {
+	tests := []struct {
+		name               string
+		first, curr, total int64
+		stopped            bool
+		output             string
+	}{
+		{"Remaining", 0, 50, 100, false, "10s"},
+		{"StartedPartway", 40, 50, 100, false, "50s"},
+		{"Done", 0, 100, 100, true, "0s"},
+		{"Stopped", 0, 50, 100, true, "?"},
+		{"Unknown", 0, 50, 0, false, "?"},
+		{"NoProgress", 0, 0, 100, false, "?"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := cmpbtest.NewClock(time.Now())
+			start := clock.Now()
+			f := cmpb.CalcETA(newAvg)(clock)
+			f(test.first, test.total, start, false)
+			clock.Advance(10 * time.Second)
+			if output := f(test.curr, test.total, start, test.stopped); output != test.output {
+				t.Error("want", test.output, "got", output)
+			}
+		})
+	}
+}
+
+func TestCalcRate(t *testing.T) {
+	clock := cmpbtest.NewClock(time.Now())
+	f := cmpb.CalcRate(newAvg)(clock)
+	f(0, 100, clock.Now(), false)
+	clock.Advance(10 * time.Second)
+	if output := f(50, 100, clock.Now(), false); output != "5.0/s" {
+		t.Error("want", "5.0/s", "got", output)
+	}
+}
+
+func TestCalcETASteps(t *testing.T) {
+	clock := cmpbtest.NewClock(time.Now())
+	start := clock.Now()
+	f := cmpb.CalcETA(newAvg)(clock)
+
+	if output := f(0, 100, start, false); output != "?" {
+		t.Error("want", "?", "got", output)
+	}
+	clock.Advance(10 * time.Second)
+	if output := f(25, 100, start, false); output != "30s" {
+		t.Error("want", "30s", "got", output)
+	}
+	clock.Advance(10 * time.Second)
+	if output := f(75, 100, start, false); output != "7s" {
+		t.Error("want", "7s", "got", output)
+	}
+}
+
+func TestDecoratorFactory(t *testing.T) {
+	clock := cmpbtest.NewClock(time.Now())
+	param := cmpb.DefaultParam()
+	param.Out, param.Refresh, param.Clock = new(bytes.Buffer), cmpb.RefreshManual, clock
+	p := cmpb.NewWithParam(param)
+	b1 := p.NewBar("b1", 100)
+	p.SetPreBarFactory(cmpb.CalcRate(newAvg))
+	// Bars added later get their own decorator too
+	b2 := p.NewBar("b2", 100)
+
+	p.RenderFrame()
+	clock.Advance(10 * time.Second)
+	b1.Update(50)
+	b2.Update(10)
+	lines := strings.Split(p.RenderFrame(), "\n")
+
+	for i, want := range []string{"5.0/s", "1.0/s"} {
+		if !strings.Contains(lines[i], want) {
+			t.Error("want", want, "in", lines[i])
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package cmpb
+
+import (
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// ColorDepth is how many colors the terminal can display
+type ColorDepth int
+
+const (
+	// ColorAuto detects the color depth from the environment
+	ColorAuto ColorDepth = iota
+	// ColorNone disables colors
+	ColorNone
+	// Color16 is the 16 standard ANSI colors
+	Color16
+	// Color256 is the 256 color xterm palette
+	Color256
+	// ColorTrue is 24-bit truecolor
+	ColorTrue
+)
+
+// detectColorDepth picks the color depth for out from the environment. FORCE_COLOR (0 to 3, or any
+// other value for at least 16 colors) takes priority, followed by NO_COLOR, TERM=dumb and whether
+// out is a terminal at all. Otherwise, COLORTERM and TERM decide how many colors there are
+func detectColorDepth(out io.Writer) ColorDepth {
+	if force, ok := os.LookupEnv("FORCE_COLOR"); ok {
+		switch strings.ToLower(force) {
+		case "0", "false":
+			return ColorNone
+		case "2":
+			return Color256
+		case "3":
+			return ColorTrue
+		}
+		if depth := termColorDepth(); depth > Color16 {
+			return depth
+		}
+		return Color16
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" || !isTerminal(out) {
+		return ColorNone
+	}
+	return termColorDepth()
+}
+
+// termColorDepth returns how many colors the terminal claims to support
+func termColorDepth() ColorDepth {
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return ColorTrue
+	}
+	if strings.Contains(os.Getenv("TERM"), "256color") {
+		return Color256
+	}
+	return Color16
+}
+
+// RGB is a 24-bit color that is downgraded to the nearest color the terminal can display
+type RGB struct {
+	R, G, B uint8
+}
+
+// Color returns a function that colors text with c using the given color depth. The text is
+// always colored unless the depth is ColorNone
+func (c RGB) Color(depth ColorDepth) func(string, ...interface{}) string {
+	if depth == ColorNone {
+		return noOp
+	}
+	return colorFunc(c.attrs(depth))
+}
+
+// attrs returns the attributes that set the foreground to c using the given color depth
+func (c RGB) attrs(depth ColorDepth) []color.Attribute {
+	switch depth {
+	case ColorTrue:
+		return []color.Attribute{38, 2, color.Attribute(c.R), color.Attribute(c.G), color.Attribute(c.B)}
+	case Color256:
+		return []color.Attribute{38, 5, color.Attribute(c.to256())}
+	}
+	return []color.Attribute{color.Attribute(c.to16())}
+}
+
+// colorFunc returns a function that colors text with attrs, even if color.NoColor is set since the
+// color depth has already taken that into account
+func colorFunc(attrs []color.Attribute) func(string, ...interface{}) string {
+	c := color.New(attrs...)
+	c.EnableColor()
+	return c.SprintfFunc()
+}
+
+// ansi16 is the typical (xterm) palette of the 16 standard colors
+var ansi16 = []RGB{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0}, {0, 0, 238}, {205, 0, 205}, {0, 205, 205},
+	{229, 229, 229}, {127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0}, {92, 92, 255},
+	{255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// to16 returns the foreground attribute of the nearest of the 16 standard colors
+func (c RGB) to16() int {
+	nearest := 0
+	for i, std := range ansi16 {
+		if c.distance(std) < c.distance(ansi16[nearest]) {
+			nearest = i
+		}
+	}
+	if nearest < 8 {
+		return 30 + nearest
+	}
+	return 90 + nearest - 8
+}
+
+// cubeLevels are the values of each component in the 6x6x6 color cube of the 256 color palette
+var cubeLevels = []int{0, 95, 135, 175, 215, 255}
+
+// to256 returns the index of the nearest color in the color cube or grayscale ramp of the 256
+// color palette
+func (c RGB) to256() int {
+	level := func(v uint8) int {
+		switch {
+		case v < 48:
+			return 0
+		case v < 115:
+			return 1
+		}
+		return (int(v) - 35) / 40
+	}
+	r, g, b := level(c.R), level(c.G), level(c.B)
+	cube := RGB{uint8(cubeLevels[r]), uint8(cubeLevels[g]), uint8(cubeLevels[b])}
+
+	// The grayscale ramp runs from 8 to 238 in steps of 10
+	gray := (int(c.R) + int(c.G) + int(c.B)) / 3
+	step := (gray - 3) / 10
+	if step < 0 {
+		step = 0
+	} else if step > 23 {
+		step = 23
+	}
+	v := uint8(8 + step*10)
+	if c.distance(RGB{v, v, v}) < c.distance(cube) {
+		return 232 + step
+	}
+	return 16 + 36*r + 6*g + b
+}
+
+func (c RGB) distance(o RGB) int {
+	dr, dg, db := int(c.R)-int(o.R), int(c.G)-int(o.G), int(c.B)-int(o.B)
+	return dr*dr + dg*dg + db*db
+}
+
+// FillColor returns the color of the filled part of a bar given how complete it is (from 0 to 1)
+// and the color depth of the terminal
+type FillColor func(fraction float64, depth ColorDepth) func(string, ...interface{}) string
+
+// Gradient returns a FillColor that blends between the given colors, which are spread evenly
+// from empty to complete
+func Gradient(stops ...RGB) FillColor {
+	return func(fraction float64, depth ColorDepth) func(string, ...interface{}) string {
+		switch len(stops) {
+		case 0:
+			return noOp
+		case 1:
+			return stops[0].Color(depth)
+		}
+		pos := math.Min(math.Max(fraction, 0), 1) * float64(len(stops)-1)
+		i := int(pos)
+		if i >= len(stops)-1 {
+			return stops[len(stops)-1].Color(depth)
+		}
+		t := pos - float64(i)
+		from, to := stops[i], stops[i+1]
+		blend := func(a, b uint8) uint8 {
+			return uint8(math.Floor(float64(a) + t*(float64(b)-float64(a)) + 0.5))
+		}
+		return RGB{blend(from.R, to.R), blend(from.G, to.G), blend(from.B, to.B)}.Color(depth)
+	}
+}
+
+// RedYellowGreen returns a Gradient from red when empty through yellow to green when complete
+func RedYellowGreen() FillColor {
+	return Gradient(RGB{255, 0, 0}, RGB{255, 255, 0}, RGB{0, 255, 0})
+}
+
+// Threshold is the color of a bar once it is at least From complete (from 0 to 1)
+type Threshold struct {
+	From  float64
+	Color RGB
+}
+
+// Thresholds returns a FillColor that uses the color of the highest threshold reached. Thresholds
+// must be given in ascending order. Below the first threshold, the bar isn't colored
+func Thresholds(thresholds ...Threshold) FillColor {
+	return func(fraction float64, depth ColorDepth) func(string, ...interface{}) string {
+		f := noOp
+		for _, t := range thresholds {
+			if fraction < t.From {
+				break
+			}
+			f = t.Color.Color(depth)
+		}
+		return f
+	}
+}
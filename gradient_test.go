@@ -0,0 +1,111 @@
+package cmpb_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/nu11ptr/cmpb"
+)
+
+// forceColor makes the color functions output escape codes even though the output isn't a terminal
+func forceColor() (restore func()) {
+	old := color.NoColor
+	color.NoColor = false
+	return func() { color.NoColor = old }
+}
+
+func TestRGB(t *testing.T) {
+	defer forceColor()()
+
+	tests := []struct {
+		name   string
+		rgb    cmpb.RGB
+		depth  cmpb.ColorDepth
+		output string
+	}{
+		{"True", cmpb.RGB{255, 128, 0}, cmpb.ColorTrue, "\x1b[38;2;255;128;0mx\x1b[0m"},
+		{"256Cube", cmpb.RGB{255, 0, 0}, cmpb.Color256, "\x1b[38;5;196mx\x1b[0m"},
+		{"256Exact", cmpb.RGB{135, 135, 135}, cmpb.Color256, "\x1b[38;5;102mx\x1b[0m"},
+		{"256Gray", cmpb.RGB{128, 128, 128}, cmpb.Color256, "\x1b[38;5;244mx\x1b[0m"},
+		{"16Bright", cmpb.RGB{255, 0, 0}, cmpb.Color16, "\x1b[91mx\x1b[0m"},
+		{"16Dark", cmpb.RGB{200, 10, 0}, cmpb.Color16, "\x1b[31mx\x1b[0m"},
+		{"16White", cmpb.RGB{240, 240, 240}, cmpb.Color16, "\x1b[37mx\x1b[0m"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if output := test.rgb.Color(test.depth)("x"); output != test.output {
+				t.Errorf("want %q got %q", test.output, output)
+			}
+		})
+	}
+}
+
+func TestGradient(t *testing.T) {
+	defer forceColor()()
+
+	tests := []struct {
+		name     string
+		fill     cmpb.FillColor
+		fraction float64
+		want     cmpb.RGB
+	}{
+		{"Start", cmpb.RedYellowGreen(), 0, cmpb.RGB{255, 0, 0}},
+		{"Quarter", cmpb.RedYellowGreen(), 0.25, cmpb.RGB{255, 128, 0}},
+		{"Middle", cmpb.RedYellowGreen(), 0.5, cmpb.RGB{255, 255, 0}},
+		{"End", cmpb.RedYellowGreen(), 1, cmpb.RGB{0, 255, 0}},
+		{"Over", cmpb.RedYellowGreen(), 2, cmpb.RGB{0, 255, 0}},
+		{"Single", cmpb.Gradient(cmpb.RGB{1, 2, 3}), 0.7, cmpb.RGB{1, 2, 3}},
+		{"BelowThreshold", cmpb.Thresholds(cmpb.Threshold{0.5, cmpb.RGB{0, 0, 255}},
+			cmpb.Threshold{0.9, cmpb.RGB{0, 255, 0}}), 0.8, cmpb.RGB{0, 0, 255}},
+		{"AtThreshold", cmpb.Thresholds(cmpb.Threshold{0.5, cmpb.RGB{0, 0, 255}},
+			cmpb.Threshold{0.9, cmpb.RGB{0, 255, 0}}), 0.9, cmpb.RGB{0, 255, 0}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want := test.want.Color(cmpb.ColorTrue)("x")
+			if output := test.fill(test.fraction, cmpb.ColorTrue)("x"); output != want {
+				t.Errorf("want %q got %q", want, output)
+			}
+		})
+	}
+
+	// Below the first threshold, the bar isn't colored
+	fill := cmpb.Thresholds(cmpb.Threshold{0.5, cmpb.RGB{0, 0, 255}})
+	if output := fill(0.1, cmpb.ColorTrue)("x"); output != "x" {
+		t.Errorf("want %q got %q", "x", output)
+	}
+}
+
+func TestFillColors(t *testing.T) {
+	defer forceColor()()
+
+	param := cmpb.DefaultParam()
+	param.BarWidth, param.ColorDepth = 12, cmpb.Color256
+	p := cmpb.NewWithParam(param)
+	b1, b2 := p.NewBar("b1", 10), p.NewBar("b2", 10)
+	colors := cmpb.DefaultColors()
+	colors.Fill = cmpb.RedYellowGreen()
+	colors.Succeeded = color.New(color.FgGreen).SprintfFunc()
+	p.SetColors(colors)
+	b1.Update(5)
+	b2.Update(10)
+
+	tests := []struct {
+		name   string
+		bar    *cmpb.Bar
+		output string
+	}{
+		{"Running", b1, "[\x1b[38;5;226m====\x1b[0m\x1b[38;5;226m>\x1b[0m-----]"},
+		{"Succeeded", b2, "[\x1b[32m==========\x1b[0m]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if output := test.bar.String(); !strings.Contains(output, test.output) {
+				t.Errorf("want %q got %q", test.output, output)
+			}
+		})
+	}
+}
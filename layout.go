@@ -0,0 +1,114 @@
+package cmpb
+
+import (
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"github.com/nu11ptr/cmpb/strutil"
+)
+
+// layout holds the widths of the flexible columns used to render a single frame
+type layout struct {
+	msgWidth, barWidth int
+	// width is the full width of a rendered bar
+	width int
+}
+
+// fixedWidth returns the width of all the columns that never change size
+func (p *Param) fixedWidth() int {
+	return p.PrePad + p.KeyWidth + p.PreBarWidth + p.PostBarWidth + 5 // spaces + keyDiv
+}
+
+// layout calculates the column widths so a bar fits within the given output width. The message
+// and bar shrink (message first) down to their minimums when the output is too narrow and, if
+// allowed, grow to fill the output when it is wider. A width of zero means the output width is
+// unknown and the configured widths are used as is
+func (p *Param) layout(width int) layout {
+	l := layout{msgWidth: p.MsgWidth, barWidth: p.BarWidth}
+	if width > 0 {
+		// Leave the last column free as writing to it causes some terminals to wrap
+		extra := (width - 1) - p.fixedWidth() - l.msgWidth - l.barWidth
+		switch {
+		case extra > 0 && p.GrowMsg && p.GrowBar:
+			l.msgWidth += extra / 2
+			l.barWidth += extra - extra/2
+		case extra > 0 && p.GrowMsg:
+			l.msgWidth += extra
+		case extra > 0 && p.GrowBar:
+			l.barWidth += extra
+		case extra < 0:
+			extra = -shrink(&l.msgWidth, p.minMsgWidth(), -extra)
+			shrink(&l.barWidth, p.minBarWidth(), -extra)
+		}
+	}
+	l.width = p.fixedWidth() + l.msgWidth + l.barWidth
+	return l
+}
+
+// shrink reduces *col by up to n without going below min and returns how much remains to shrink
+func shrink(col *int, min, n int) int {
+	avail := *col - min
+	if avail <= 0 {
+		return n
+	}
+	if avail > n {
+		avail = n
+	}
+	*col -= avail
+	return n - avail
+}
+
+func (p *Param) minMsgWidth() int {
+	// The message can never be narrower than the post string used to mark truncation
+	if postLen := strutil.Len(p.Post); p.MinMsgWidth < postLen {
+		return postLen
+	}
+	return p.MinMsgWidth
+}
+
+func (p *Param) minBarWidth() int {
+	// Always leave room for the brackets
+	if p.MinBarWidth < 2 {
+		return 2
+	}
+	return p.MinBarWidth
+}
+
+// rows returns how many terminal rows a line of the given length occupies at the given width
+func rows(lineLen, width int) int {
+	if width <= 0 || lineLen <= width {
+		return 1
+	}
+	return (lineLen + width - 1) / width
+}
+
+// outputFd returns the file descriptor out writes to, if it has one
+func outputFd(out io.Writer) (uintptr, bool) {
+	// On Windows the default output is wrapped to translate escape codes, but it is still stdout
+	if out == color.Output {
+		out = os.Stdout
+	}
+	if f, ok := out.(interface {
+		Fd() uintptr
+	}); ok {
+		return f.Fd(), true
+	}
+	return 0, false
+}
+
+// outputSize returns the width and height of the terminal out writes to, or zeros if it isn't a
+// terminal
+func outputSize(out io.Writer) (int, int) {
+	if fd, ok := outputFd(out); ok {
+		return termSize(fd)
+	}
+	return 0, 0
+}
+
+// isTerminal returns true if out writes to a terminal
+func isTerminal(out io.Writer) bool {
+	fd, ok := outputFd(out)
+	return ok && (isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd))
+}
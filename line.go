@@ -0,0 +1,48 @@
+package cmpb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lineState is the state of a bar as it was last printed in line mode
+type lineState struct {
+	step    int64
+	msg     string
+	stopped bool
+}
+
+// snapshot returns the parts of the bar that decide whether it is printed in line mode
+func (b *Bar) snapshot() lineState {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	state := lineState{step: -1, msg: b.msg, stopped: b.stopped}
+	if b.total > 0 && b.p.LineStep > 0 {
+		state.step = scale(b.curr, b.total, 100) / int64(b.p.LineStep)
+	}
+	return state
+}
+
+// renderLines prints each bar that has meaningfully changed since it was last printed - when it is
+// first seen, advances by another LineStep percent, changes message or stops - on its own line
+// without any cursor movement
+func (p *Progress) renderLines() {
+	p.flushLogs(p.logW.take())
+	l := p.param.layout(0)
+	for _, bar := range p.withSummary(p.treeOrder()) {
+		state := bar.snapshot()
+		last, ok := p.lines[bar]
+		if ok && (last.stopped || (state == *last)) {
+			continue
+		}
+		p.lines[bar] = &state
+		fmt.Fprintln(p.param.Out, bar.render(l))
+
+		if extMsg := bar.extendedMsg(); state.stopped && extMsg != "" {
+			for _, line := range strings.Split(extMsg, "\n") {
+				fmt.Fprintln(p.param.Out, "        "+line)
+			}
+		}
+	}
+}
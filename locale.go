@@ -0,0 +1,29 @@
+package cmpb
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// unicodeSupported guesses whether the terminal can display Unicode block characters from the
+// environment: the terminal type and the character set of the locale
+func unicodeSupported() bool {
+	switch os.Getenv("TERM") {
+	case "dumb", "linux":
+		// The Linux console font lacks most block characters
+		return false
+	}
+	// Windows Terminal always supports Unicode, while the legacy console rarely sets a locale
+	if runtime.GOOS == "windows" && os.Getenv("WT_SESSION") != "" {
+		return true
+	}
+	// The first of these that is set decides the locale
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			locale = strings.ToLower(locale)
+			return strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8")
+		}
+	}
+	return false
+}
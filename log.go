@@ -0,0 +1,96 @@
+package cmpb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+)
+
+// logWriter buffers writes until they form complete lines. While the bars are being rendered, the
+// lines are held until the next render, which prints them above the bars, otherwise they are
+// written straight through
+type logWriter struct {
+	mut       sync.Mutex
+	out       io.Writer
+	partial   []byte
+	pending   []string
+	rendering bool
+	// notify (if set) is called whenever a line is held for the next render
+	notify func()
+}
+
+func (w *logWriter) Write(b []byte) (int, error) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	w.partial = append(w.partial, b...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(w.partial[:i])
+		w.partial = w.partial[i+1:]
+		if w.rendering {
+			w.pending = append(w.pending, line)
+			if w.notify != nil {
+				w.notify()
+			}
+		} else {
+			fmt.Fprintln(w.out, line)
+		}
+	}
+	return len(b), nil
+}
+
+// take returns the lines waiting to be printed, removing them from the writer
+func (w *logWriter) take() []string {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	lines := w.pending
+	w.pending = nil
+	return lines
+}
+
+// setRendering sets whether the bars are being rendered. Once rendering stops, anything still
+// buffered (including an unterminated line) is written out
+func (w *logWriter) setRendering(rendering bool) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	w.rendering = rendering
+	if rendering {
+		return
+	}
+	for _, line := range w.pending {
+		fmt.Fprintln(w.out, line)
+	}
+	if len(w.partial) > 0 {
+		fmt.Fprintln(w.out, string(w.partial))
+	}
+	w.pending, w.partial = nil, nil
+}
+
+// Writer returns a writer for log output. Complete lines written to it while the bars are being
+// rendered are printed above the bars on the next render, so they don't corrupt the display
+func (p *Progress) Writer() io.Writer {
+	return p.logW
+}
+
+// RedirectLog sets the output of the standard log package to Writer. The returned function sets it
+// back to the previous output
+func (p *Progress) RedirectLog() (restore func()) {
+	prev := log.Writer()
+	log.SetOutput(p.logW)
+	return func() { log.SetOutput(prev) }
+}
+
+// flushLogs prints any lines written to Writer since the last render
+func (p *Progress) flushLogs(lines []string) {
+	for _, line := range lines {
+		fmt.Fprintln(p.param.Out, line)
+	}
+}
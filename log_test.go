@@ -0,0 +1,58 @@
+package cmpb_test
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriter(t *testing.T) {
+	out := new(bytes.Buffer)
+	p := newTestProgress(out)
+	b := p.NewBar("log", 10)
+
+	fmt.Fprintln(p.Writer(), "before start")
+	p.Start()
+	fmt.Fprint(p.Writer(), "during ")
+	fmt.Fprintln(p.Writer(), "render")
+	fmt.Fprint(p.Writer(), "unterminated")
+	// Let a few frames render
+	time.Sleep(20 * time.Millisecond)
+	b.Update(10)
+	p.Wait()
+
+	output := out.String()
+	before := strings.Index(output, "before start\n")
+	during := strings.Index(output, "\x1b[Jduring render\n")
+	final := strings.LastIndex(output, "log       :")
+	after := strings.Index(output, "unterminated\n")
+	if before != 0 || during < 0 || final < during || after < final {
+		t.Errorf("log lines out of place: %q", output)
+	}
+}
+
+func TestRedirectLog(t *testing.T) {
+	prev, flags := log.Writer(), log.Flags()
+	defer log.SetOutput(prev)
+	defer log.SetFlags(flags)
+	orig := new(bytes.Buffer)
+	log.SetOutput(orig)
+	log.SetFlags(0)
+
+	out := new(bytes.Buffer)
+	p := newTestProgress(out)
+	restore := p.RedirectLog()
+	log.Println("redirected")
+	restore()
+	log.Println("restored")
+
+	if out.String() != "redirected\n" {
+		t.Errorf("want %q got %q", "redirected\n", out.String())
+	}
+	if orig.String() != "restored\n" {
+		t.Errorf("want %q got %q", "restored\n", orig.String())
+	}
+}
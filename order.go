@@ -0,0 +1,71 @@
+package cmpb
+
+import "sort"
+
+// ByKey orders bars by their key
+func ByKey(a, b *Bar) bool {
+	ai, bi := a.info(), b.info()
+	return ai.key < bi.key
+}
+
+// ByProgress orders bars from the least to the most complete. Bars without a total are treated as
+// not started until they finish
+func ByProgress(a, b *Bar) bool {
+	ai, bi := a.info(), b.info()
+	return ai.fraction() < bi.fraction()
+}
+
+// ByStart orders bars from the earliest to the most recently created
+func ByStart(a, b *Bar) bool {
+	ai, bi := a.info(), b.info()
+	return ai.start.Before(bi.start)
+}
+
+// SortBars reorders the bars using less (such as ByKey, ByProgress or ByStart). Children are only
+// reordered among their siblings. Bars added later are still added after the existing ones
+func (p *Progress) SortBars(less func(a, b *Bar) bool) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	// Parents need to be up to date to be compared
+	p.aggregate()
+	sort.SliceStable(p.bars, func(i, j int) bool { return less(p.bars[i], p.bars[j]) })
+	for _, b := range p.bars {
+		children := append([]*Bar(nil), b.childBars()...)
+		sort.SliceStable(children, func(i, j int) bool { return less(children[i], children[j]) })
+		b.mut.Lock()
+		b.children = children
+		b.mut.Unlock()
+	}
+}
+
+// MoveToTop moves the bar with the given key above all others (or above its siblings if it is a
+// child). Returns false if there is no bar with the given key
+func (p *Progress) MoveToTop(key string) bool {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	b, ok := p.barMap[key]
+	if !ok {
+		return false
+	}
+	p.bars = moveToFront(p.bars, b)
+	if parent := b.parent; parent != nil {
+		parent.mut.Lock()
+		parent.children = moveToFront(parent.children, b)
+		parent.mut.Unlock()
+	}
+	return true
+}
+
+// moveToFront returns a copy of bars with b moved to the front
+func moveToFront(bars []*Bar, b *Bar) []*Bar {
+	moved := make([]*Bar, 1, len(bars))
+	moved[0] = b
+	for _, bar := range bars {
+		if bar != b {
+			moved = append(moved, bar)
+		}
+	}
+	return moved
+}
@@ -0,0 +1,52 @@
+package cmpb_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nu11ptr/cmpb"
+)
+
+func TestSortBars(t *testing.T) {
+	tests := []struct {
+		name     string
+		order    func(p *cmpb.Progress)
+		expected []string
+	}{
+		{"ByKey", func(p *cmpb.Progress) { p.SortBars(cmpb.ByKey) },
+			[]string{"a", "b", "  x", "  y", "c"}},
+		{"ByProgress", func(p *cmpb.Progress) { p.SortBars(cmpb.ByProgress) },
+			[]string{"a", "c", "b", "  y", "  x"}},
+		{"ByStart", func(p *cmpb.Progress) { p.SortBars(cmpb.ByStart) },
+			[]string{"c", "a", "b", "  y", "  x"}},
+		{"MoveToTop", func(p *cmpb.Progress) { p.MoveToTop("b"); p.MoveToTop("x") },
+			[]string{"b", "  x", "  y", "c", "a"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			p := newTestProgress(out)
+			p.NewBar("c", 10).Update(5)
+			p.NewBar("a", 10).Update(1)
+			b := p.NewBar("b", 0)
+			p.NewChildBar(b, "y", 10).Update(10)
+			p.NewChildBar(b, "x", 10).Update(10)
+			test.order(p)
+
+			p.Start()
+			p.Bar("a").Update(10)
+			p.Bar("c").Update(10)
+			p.Wait()
+
+			var keys []string
+			for _, line := range firstFrame(out) {
+				keys = append(keys, strings.TrimRight(strings.SplitN(line, ":", 2)[0], " "))
+			}
+			if strings.Join(keys, ",") != strings.Join(test.expected, ",") {
+				t.Error("want", test.expected, "got", keys)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package cmpb
+
+import (
+	"fmt"
+	"unicode"
+
+	"github.com/fatih/color"
+	"github.com/nu11ptr/cmpb/strutil"
+)
+
+// Validate checks that the parameters can be used to render bars, returning an error describing
+// the first problem found (or nil if there are none)
+func (p *Param) Validate() error {
+	switch {
+	case p.Mode < ModeAuto || p.Mode > ModeLine:
+		return fmt.Errorf("unknown Mode %d", p.Mode)
+	case p.Refresh < RefreshInterval || p.Refresh > RefreshManual:
+		return fmt.Errorf("unknown Refresh %d", p.Refresh)
+	case p.ColorDepth < ColorAuto || p.ColorDepth > ColorTrue:
+		return fmt.Errorf("unknown ColorDepth %d", p.ColorDepth)
+	case p.Interval <= 0:
+		return fmt.Errorf("Interval must be positive, got %v", p.Interval)
+	case p.Clock == nil:
+		return fmt.Errorf("Clock must not be nil")
+	case p.Out == nil:
+		return fmt.Errorf("Out must not be nil")
+	case p.ScrollUp == nil || p.CursorDown == nil || p.ClearDown == nil:
+		return fmt.Errorf("ScrollUp, CursorDown and ClearDown must not be nil")
+	case p.BarWidth < 2:
+		return fmt.Errorf("BarWidth must be at least 2 (for the brackets), got %d", p.BarWidth)
+	case p.RemoveAfter < 0:
+		return fmt.Errorf("RemoveAfter must not be negative, got %v", p.RemoveAfter)
+	}
+
+	widths := []struct {
+		name  string
+		width int
+	}{
+		{"PrePad", p.PrePad}, {"KeyWidth", p.KeyWidth}, {"MsgWidth", p.MsgWidth},
+		{"PreBarWidth", p.PreBarWidth}, {"PostBarWidth", p.PostBarWidth},
+		{"BounceWidth", p.BounceWidth}, {"Width", p.Width}, {"Height", p.Height},
+		{"MinMsgWidth", p.MinMsgWidth}, {"MinBarWidth", p.MinBarWidth}, {"LineStep", p.LineStep},
+	}
+	for _, w := range widths {
+		if w.width < 0 {
+			return fmt.Errorf("%s must not be negative, got %d", w.name, w.width)
+		}
+	}
+	// The message and bar can only shrink
+	switch {
+	case p.MinMsgWidth > p.MsgWidth:
+		return fmt.Errorf("MinMsgWidth (%d) must not be more than MsgWidth (%d)", p.MinMsgWidth,
+			p.MsgWidth)
+	case p.MinBarWidth > p.BarWidth:
+		return fmt.Errorf("MinBarWidth (%d) must not be more than BarWidth (%d)", p.MinBarWidth,
+			p.BarWidth)
+	}
+
+	// Each of these columns is truncated using Post when its contents are too long
+	postLen := strutil.Len(p.Post)
+	columns := []struct {
+		name  string
+		width int
+	}{
+		{"KeyWidth", p.KeyWidth}, {"MsgWidth", p.MsgWidth}, {"PreBarWidth", p.PreBarWidth},
+		{"PostBarWidth", p.PostBarWidth},
+	}
+	for _, c := range columns {
+		if c.width < postLen {
+			return fmt.Errorf("%s (%d) must be at least the length of Post %q", c.name, c.width, p.Post)
+		}
+	}
+
+	runes := []struct {
+		name string
+		r    rune
+	}{
+		{"KeyDiv", p.KeyDiv}, {"LBracket", p.LBracket}, {"RBracket", p.RBracket},
+		{"Empty", p.Empty}, {"Full", p.Full}, {"Curr", p.Curr},
+	}
+	for _, r := range runes {
+		if !unicode.IsPrint(r.r) {
+			return fmt.Errorf("%s must be a printable character, got %q", r.name, r.r)
+		}
+	}
+	for _, r := range p.Spinner {
+		if !unicode.IsPrint(r) {
+			return fmt.Errorf("Spinner must only contain printable characters, got %q", p.Spinner)
+		}
+	}
+	return nil
+}
+
+// sanitize replaces any parameters that would stop the bars from rendering at all with their
+// defaults (or the nearest usable value)
+func (p *Param) sanitize() {
+	if p.Interval <= 0 {
+		p.Interval = defaultInterval
+	}
+	if p.Clock == nil {
+		p.Clock = SystemClock
+	}
+	if p.Out == nil {
+		p.Out = color.Output
+	}
+	if p.ScrollUp == nil {
+		p.ScrollUp = AnsiScrollUp
+	}
+	if p.CursorDown == nil {
+		p.CursorDown = AnsiCursorDown
+	}
+	if p.ClearDown == nil {
+		p.ClearDown = AnsiClearDown
+	}
+	if p.BarWidth < 2 {
+		p.BarWidth = 2
+	}
+	for _, width := range []*int{
+		&p.PrePad, &p.KeyWidth, &p.MsgWidth, &p.PreBarWidth, &p.PostBarWidth, &p.BounceWidth,
+		&p.Width, &p.Height,
+	} {
+		if *width < 0 {
+			*width = 0
+		}
+	}
+	if p.MinMsgWidth > p.MsgWidth {
+		p.MinMsgWidth = p.MsgWidth
+	}
+	if p.MinBarWidth > p.BarWidth {
+		p.MinBarWidth = p.BarWidth
+	}
+}
+
+// NewWithParamErr creates a new progress bar collection with specified params, returning an error
+// instead if they are not valid
+func NewWithParamErr(param *Param) (*Progress, error) {
+	if err := param.Validate(); err != nil {
+		return nil, err
+	}
+	return NewWithParam(param), nil
+}
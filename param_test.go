@@ -0,0 +1,79 @@
+package cmpb_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nu11ptr/cmpb"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(p *cmpb.Param)
+		err    string
+	}{
+		{"Default", func(p *cmpb.Param) {}, ""},
+		{"Mode", func(p *cmpb.Param) { p.Mode = 7 }, "unknown Mode 7"},
+		{"Interval", func(p *cmpb.Param) { p.Interval = 0 }, "Interval must be positive, got 0s"},
+		{"Out", func(p *cmpb.Param) { p.Out = nil }, "Out must not be nil"},
+		{"BarWidth", func(p *cmpb.Param) { p.BarWidth = 1 },
+			"BarWidth must be at least 2 (for the brackets), got 1"},
+		{"Negative", func(p *cmpb.Param) { p.PrePad = -1 }, "PrePad must not be negative, got -1"},
+		{"NegativeColumn", func(p *cmpb.Param) { p.Post, p.MsgWidth = "", -1 },
+			"MsgWidth must not be negative, got -1"},
+		{"MinMsgWidth", func(p *cmpb.Param) { p.MinMsgWidth = p.MsgWidth + 1 },
+			"MinMsgWidth (21) must not be more than MsgWidth (20)"},
+		{"MinBarWidth", func(p *cmpb.Param) { p.MinBarWidth = 30 },
+			"MinBarWidth (30) must not be more than BarWidth (22)"},
+		{"Post", func(p *cmpb.Param) { p.Post = "......" },
+			`PostBarWidth (4) must be at least the length of Post "......"`},
+		{"Rune", func(p *cmpb.Param) { p.Full = 0 }, `Full must be a printable character, got '\x00'`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			param := cmpb.DefaultParam()
+			test.change(param)
+			err := param.Validate()
+			switch {
+			case test.err == "" && err != nil:
+				t.Error("want nil got", err)
+			case test.err != "" && (err == nil || err.Error() != test.err):
+				t.Error("want", test.err, "got", err)
+			}
+
+			p, err := cmpb.NewWithParamErr(param)
+			if (test.err == "") != (p != nil && err == nil) {
+				t.Error("want progress only when valid got", p, err)
+			}
+		})
+	}
+}
+
+func TestInvalidParam(t *testing.T) {
+	out := new(bytes.Buffer)
+	p := newTestProgress(out, func(p *cmpb.Param) {
+		p.Interval, p.ScrollUp, p.PrePad, p.BarWidth, p.PostBarWidth = 0, nil, -2, 0, 1
+	})
+	p.NewBar("bar", 10).Update(5)
+	broken := p.NewBar("broken", 10)
+	broken.SetPreBar(func(int64, int64, time.Time, bool) string { panic("oops") })
+
+	p.Start()
+	broken.Update(10)
+	p.Bar("bar").Update(10)
+	if err := p.Wait(); err != nil {
+		t.Fatal("want nil got", err)
+	}
+
+	expected := []string{
+		"bar       :                               0s [] .",
+		"broken: render failed: oops",
+	}
+	if first := firstFrame(out); strings.Join(first, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("want %q got %q", expected, first)
+	}
+}
@@ -1,14 +1,16 @@
 package cmpb
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/fatih/color"
-	"github.com/nu11ptr/cmpb/strutil"
 )
 
 const (
@@ -20,43 +22,144 @@ const (
 	defaultPreBarWidth  = 11 // Duration (max size = 00h 00m 00s)
 	defaultBarWidth     = 22 // Each char = 5% (+2 for left and right bracket)
 	defaultPostBarWidth = 4  // Percentage (max size = 100%)
+	defaultBounceWidth  = 4
+	defaultMinMsgWidth  = 8
+	defaultMinBarWidth  = 12 // Each char = 10%
+	defaultLineStep     = 10 // Percent
 
 	slMapCap = 16
 )
 
 var (
-	defaultPost     = "..."
-	defaultKeyDiv   = ':'
-	defaultLBracket = '['
-	defaultRBracket = ']'
-	defaultEmpty    = '-'
-	defaultFull     = '='
-	defaultCurr     = '>'
+	defaultPost      = "..."
+	defaultCancelMsg = "cancelled"
+	defaultKeyDiv    = ':'
+	defaultLBracket  = '['
+	defaultRBracket  = ']'
+	defaultEmpty     = '-'
+	defaultFull      = '='
+	defaultCurr      = '>'
+	defaultSpinner   = `|/-\`
+
+	defaultSucceedMsg   = "done"
+	defaultFailMsg      = "failed"
+	defaultSkipMsg      = "skipped"
+	defaultSucceedGlyph = '✓'
+	defaultFailGlyph    = '✗'
+	defaultSkipGlyph    = '↷'
+	defaultCancelGlyph  = '⊘'
+)
+
+var (
+	// ErrStarted is returned by Start when the bars are already being rendered
+	ErrStarted = errors.New("progress already started")
+	// ErrNotStarted is returned by Wait when there are unfinished bars but nothing is rendering them
+	ErrNotStarted = errors.New("progress not started")
+)
+
+// Mode represents how a Progress renders its bars
+type Mode int
+
+const (
+	// ModeAuto uses ModeTerm when the output is a terminal and ModeLine otherwise
+	ModeAuto Mode = iota
+	// ModeTerm redraws the bars in place using cursor movement
+	ModeTerm
+	// ModeLine never moves the cursor and instead prints a new line for a bar each time it
+	// changes meaningfully (for CI logs, files and pipes)
+	ModeLine
+)
+
+// Refresh represents when a Progress renders a new frame
+type Refresh int
+
+const (
+	// RefreshInterval renders a new frame every Interval
+	RefreshInterval Refresh = iota
+	// RefreshOnChange renders a new frame whenever a bar changes (or a line is written to Writer),
+	// but no more than once per Interval. Since nothing is rendered while the bars are unchanged,
+	// elapsed times and animations only advance along with the bars
+	RefreshOnChange
+	// RefreshManual never renders on its own - the caller renders each frame via Render or
+	// RenderFrame instead
+	RefreshManual
 )
 
 // Param represents the parameters for a Progress
 type Param struct {
+	Mode         Mode
+	Refresh      Refresh
 	Interval     time.Duration
 	Out          io.Writer
 	ScrollUp     func(int, io.Writer)
+	CursorDown   func(int, io.Writer)
+	ClearDown    func(io.Writer)
 	InlineExtMsg bool
-
-	PrePad, KeyWidth, MsgWidth, PreBarWidth, BarWidth, PostBarWidth int
-
-	Post                                          string
+	// FullRedraw redraws every line each render instead of only the lines that changed
+	FullRedraw bool
+	// Clock provides the time to the bars, the render loop, the default decorators and those created
+	// by a DecoratorFactory
+	Clock Clock
+	// ColorDepth is the number of colors used for RGB colors (such as those of a Gradient) and
+	// themes. ColorAuto detects it from the environment and Out
+	ColorDepth ColorDepth
+
+	PrePad, KeyWidth, MsgWidth, PreBarWidth, BarWidth, PostBarWidth, BounceWidth int
+
+	// Width and Height are the size of the output in columns and rows. If zero, they are
+	// detected (and tracked as the terminal is resized) when Out is a terminal, otherwise the bars
+	// are never resized and the viewport never limits how many are shown
+	Width, Height int
+	// Viewport limits the bars shown to what fits within Height, collapsing the rest into a single
+	// summary line
+	Viewport bool
+	// The message and bar shrink down to these widths when the output is too narrow for them
+	MinMsgWidth, MinBarWidth int
+	// GrowMsg and GrowBar allow the message and bar to grow to fill the width of the output
+	GrowMsg, GrowBar bool
+	// LineStep is how many percent a bar must advance before it is printed again in ModeLine
+	LineStep int
+	// Smooth fills bars with Unicode block characters, using eighths of a character to show partial
+	// progress in the leading cell. It is turned off automatically (falling back to Full, Curr and
+	// Empty) when the terminal or locale can't display them
+	Smooth bool
+	// Style (if set) draws the bars instead of LBracket, RBracket, Empty, Full, Curr and Smooth.
+	// Bars can override it via SetStyle
+	Style BarStyle
+	// RemoveAfter removes top level bars (along with their children) once they have been finished
+	// for this long. If zero, finished bars are never removed automatically
+	RemoveAfter time.Duration
+	// PrintRemoved prints the final line of each bar removed automatically above the bars
+	PrintRemoved bool
+
+	Post, Spinner                                 string
 	KeyDiv, LBracket, RBracket, Empty, Full, Curr rune
+
+	// The messages shown (prefixed by their glyph, if not zero) when a bar is marked via Succeed,
+	// Fail, Skip or Cancel. A bar that succeeds by reaching its total keeps its own message instead.
+	// Glyphs that aren't ASCII are left out when the terminal or locale can't display Unicode
+	SucceedMsg, FailMsg, SkipMsg, CancelMsg         string
+	SucceedGlyph, FailGlyph, SkipGlyph, CancelGlyph rune
 }
 
 // DefaultParam builds a Param struct with default values
 func DefaultParam() *Param {
 	return &Param{
-		Interval: defaultInterval, Out: color.Output, ScrollUp: AnsiScrollUp,
+		Interval: defaultInterval, Clock: SystemClock, Out: color.Output, ScrollUp: AnsiScrollUp,
+		CursorDown: AnsiCursorDown, ClearDown: AnsiClearDown,
 
 		PrePad: defaultPrePad, KeyWidth: defaultKeyWidth, MsgWidth: defaultMsgWidth,
 		PreBarWidth: defaultPreBarWidth, BarWidth: defaultBarWidth, PostBarWidth: defaultPostBarWidth,
+		BounceWidth: defaultBounceWidth, MinMsgWidth: defaultMinMsgWidth,
+		MinBarWidth: defaultMinBarWidth, LineStep: defaultLineStep, Viewport: true,
 
-		Post: defaultPost, KeyDiv: defaultKeyDiv, LBracket: defaultLBracket,
-		RBracket: defaultRBracket, Empty: defaultEmpty, Full: defaultFull, Curr: defaultCurr,
+		Post: defaultPost, Spinner: defaultSpinner, KeyDiv: defaultKeyDiv,
+		LBracket: defaultLBracket, RBracket: defaultRBracket, Empty: defaultEmpty,
+		Full: defaultFull, Curr: defaultCurr,
+
+		SucceedMsg: defaultSucceedMsg, FailMsg: defaultFailMsg, SkipMsg: defaultSkipMsg,
+		CancelMsg: defaultCancelMsg, SucceedGlyph: defaultSucceedGlyph,
+		FailGlyph: defaultFailGlyph, SkipGlyph: defaultSkipGlyph, CancelGlyph: defaultCancelGlyph,
 	}
 }
 
@@ -64,23 +167,74 @@ func DefaultParam() *Param {
 type Progress struct {
 	param Param
 
-	quitCh      chan struct{}
-	wait        sync.WaitGroup
-	mut         sync.Mutex
-	stopped     bool
-	scrollLines int
+	mut     sync.Mutex
+	running bool
+	// pending is how many bars have yet to be rendered for the last time
+	pending int
+	// waiters is how many calls to Wait are waiting for the render loop to end
+	waiters int
+	// wakeCh wakes the render loop so it can check if it should end
+	wakeCh chan struct{}
+	// changeCh signals the render loop that a bar has changed (only used with RefreshOnChange)
+	changeCh chan struct{}
+	// loopDone is closed once the current render loop has ended
+	loopDone chan struct{}
+	width    int
+	height   int
+	resized  bool
+	// lines holds the state of each bar as last printed in line mode (nil in terminal mode)
+	lines map[*Bar]*lineState
+	logW  *logWriter
+	start time.Time
+
+	// removed counts the bars removed in each state so outcomes still include them
+	removed map[State]int
+	// removedLines are the final lines of removed bars still to be printed above the bars
+	removedLines []string
+
+	// colors (if set) are the colors of every bar, including those added later
+	colors *BarColors
+	// preBarFactory and postBarFactory (if set) create the decorators of each bar added
+	preBarFactory, postBarFactory DecoratorFactory
+
+	summary    *Bar
+	summaryPos SummaryPosition
+	// prevFrame holds the lines last rendered so we know how far to scroll back up and what changed
+	prevFrame []string
 
 	bars   []*Bar
 	barMap map[string]*Bar
 }
 
-// NewWithParam creates a new progress bar collection with specified params
+// NewWithParam creates a new progress bar collection with specified params. Any params that would
+// stop the bars from rendering at all are replaced with usable values - use NewWithParamErr to
+// reject invalid params instead
 func NewWithParam(param *Param) *Progress {
-	return &Progress{
+	p := &Progress{
 		param:  *param,
-		quitCh: make(chan struct{}),
-		bars:   make([]*Bar, 0, slMapCap), barMap: make(map[string]*Bar, slMapCap),
+		wakeCh: make(chan struct{}, 1), changeCh: make(chan struct{}, 1),
+		removed: make(map[State]int),
+		bars:    make([]*Bar, 0, slMapCap), barMap: make(map[string]*Bar, slMapCap),
+	}
+	p.param.sanitize()
+	if !unicodeSupported() {
+		p.param.Smooth = false
+		p.param.asciiGlyphs()
+	}
+	p.param.Style = p.param.style()
+	if p.param.ColorDepth == ColorAuto {
+		p.param.ColorDepth = detectColorDepth(p.param.Out)
+	}
+	p.logW = &logWriter{out: p.param.Out}
+	if p.param.Refresh == RefreshOnChange {
+		p.logW.notify = p.changed
 	}
+	if p.param.Mode == ModeLine || (p.param.Mode == ModeAuto && !isTerminal(p.param.Out)) {
+		p.lines = make(map[*Bar]*lineState, slMapCap)
+	} else {
+		p.detectSize()
+	}
+	return p
 }
 
 // New creates a new progress bar collection with default params
@@ -93,18 +247,50 @@ func AnsiScrollUp(rows int, out io.Writer) {
 	fmt.Fprintf(out, "\x1b[%dA", rows)
 }
 
+// AnsiCursorDown uses ANSI escape codes to move the cursor down without changing anything
+func AnsiCursorDown(rows int, out io.Writer) {
+	fmt.Fprintf(out, "\x1b[%dB", rows)
+}
+
+// AnsiClearDown uses ANSI escape codes to clear everything from the cursor to the end of the screen
+func AnsiClearDown(out io.Writer) {
+	fmt.Fprint(out, "\x1b[J")
+}
+
 // NewBar creates a new progress bar and adds it to the progress bar collection
-func (p *Progress) NewBar(key string, total int) *Bar {
+func (p *Progress) NewBar(key string, total int64) *Bar {
 	p.mut.Lock()
 	defer p.mut.Unlock()
 
-	if p.stopped {
-		panic("Tried to add new bar to stopped progress bar")
-	}
+	return p.addBar(key, total)
+}
+
+func (p *Progress) addBar(key string, total int64) *Bar {
 	b := newBar(key, total, &p.param)
+	if p.colors != nil {
+		b.colors = *p.colors
+	}
+	if p.preBarFactory != nil {
+		b.preBarF = p.preBarFactory(p.param.Clock)
+	}
+	if p.postBarFactory != nil {
+		b.postBarF = p.postBarFactory(p.param.Clock)
+	}
+	if p.param.Refresh == RefreshOnChange {
+		b.notify = p.changed
+	}
 	p.bars = append(p.bars, b)
 	p.barMap[key] = b
-	p.wait.Add(1)
+	p.pending++
+	return b
+}
+
+// NewIndeterminateBar creates a new progress bar whose total is not yet known and adds it to the
+// progress bar collection. It is drawn using the given animation and shows a running count until
+// a total is set via SetTotal
+func (p *Progress) NewIndeterminateBar(key string, anim Animation) *Bar {
+	b := p.NewBar(key, 0)
+	b.SetAnimation(anim)
 	return b
 }
 
@@ -118,124 +304,316 @@ func (p *Progress) Bar(key string) *Bar {
 }
 
 // SetPreBar sets the prebar function decorator
-func (p *Progress) SetPreBar(f func(int, int, time.Time, bool) string) {
+func (p *Progress) SetPreBar(f func(int64, int64, time.Time, bool) string) {
 	p.mut.Lock()
 	defer p.mut.Unlock()
 
+	p.preBarFactory = nil
 	for _, bar := range p.bars {
 		bar.SetPreBar(f)
 	}
 }
 
 // SetPostBar sets the postbar function decorator
-func (p *Progress) SetPostBar(f func(int, int, time.Time, bool) string) {
+func (p *Progress) SetPostBar(f func(int64, int64, time.Time, bool) string) {
 	p.mut.Lock()
 	defer p.mut.Unlock()
 
+	p.postBarFactory = nil
 	for _, bar := range p.bars {
 		bar.SetPostBar(f)
 	}
 }
 
-// SetColors sets the colors used to render all the bars part of this progress
-func (p *Progress) SetColors(colors *BarColors) {
+// SetPreBarFactory gives every bar (including those added later) its own prebar function decorator
+// created by f
+func (p *Progress) SetPreBarFactory(f DecoratorFactory) {
 	p.mut.Lock()
 	defer p.mut.Unlock()
 
+	p.preBarFactory = f
 	for _, bar := range p.bars {
-		bar.SetColors(colors)
+		bar.SetPreBar(f(p.param.Clock))
 	}
 }
 
-func (p *Progress) renderExtMsg(bar *Bar, barLen int) {
-	extMsg := bar.extendedMsg()
-	if extMsg == "" {
-		return
-	}
-	lines := strings.Split(extMsg, "\n")
-	p.scrollLines += len(lines)
+// SetPostBarFactory gives every bar (including those added later) its own postbar function
+// decorator created by f
+func (p *Progress) SetPostBarFactory(f DecoratorFactory) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
 
-	for _, line := range lines {
-		// Use spaces instead of tab so anything on screen is overwritten
-		str := strutil.ResizeR("        "+line, p.param.Post, barLen)
-		fmt.Fprintln(p.param.Out, str)
+	p.postBarFactory = f
+	for _, bar := range p.bars {
+		bar.SetPostBar(f(p.param.Clock))
 	}
 }
 
-func (p *Progress) render(scrollUp bool) {
+// SetColors sets the colors used to render all the bars part of this progress, including those
+// added later. If the color depth is ColorNone, the bars are left uncolored instead
+func (p *Progress) SetColors(colors *BarColors) {
 	p.mut.Lock()
 	defer p.mut.Unlock()
 
-	if scrollUp {
-		p.param.ScrollUp(p.scrollLines, p.param.Out)
+	if p.param.ColorDepth == ColorNone {
+		colors = DefaultColors()
 	}
-	// By doing this after scrollup, we calculate this based on what we actually rendered - avoiding
-	// a race condition where a msg was added but we hadn't yet rendered it
-	p.scrollLines = len(p.bars)
-	barLen := 0
+	c := *colors
+	p.colors = &c
 	for _, bar := range p.bars {
-		barStr := bar.String()
-		barLen = len(barStr)
-		fmt.Fprintln(p.param.Out, barStr)
-		if p.param.InlineExtMsg {
-			p.renderExtMsg(bar, barLen)
-		}
+		bar.SetColors(colors)
 	}
-	// If not inline, we then rendor after all bars are rendered
-	if !p.param.InlineExtMsg {
-		for _, bar := range p.bars {
-			if !p.param.InlineExtMsg {
-				p.renderExtMsg(bar, barLen)
-			}
-		}
+}
+
+func (p *Progress) render() {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	p.prepare()
+	if p.lines != nil {
+		p.renderLines()
+	} else {
+		p.renderTerm()
 	}
+	p.rendered()
+}
+
+// prepare brings everything calculated from the bars up to date before rendering a frame
+func (p *Progress) prepare() {
+	p.aggregate()
+	p.removeFinished()
+	p.updateSummary()
+}
+
+// rendered records that a frame was rendered, so finished bars are no longer waited on
+func (p *Progress) rendered() {
 	// Done as another pass so all bars are always rendered per cycle
 	for _, bar := range p.bars {
 		if bar.isLastRender() {
-			p.wait.Done()
+			p.pending--
 		}
 	}
 }
 
-// Start begins rendering of the progress bars
-func (p *Progress) Start() {
+// Render writes the next frame to the output, exactly as the bars would be rendered on their own.
+// It is mostly useful with RefreshManual, where nothing is rendered otherwise
+func (p *Progress) Render() {
+	p.render()
+	if p.param.Refresh == RefreshManual {
+		p.end()
+	}
+}
+
+// RenderFrame renders the next frame and returns it as a string (one line per row, without any
+// cursor movement) instead of writing it to the output. Lines written to Writer and the final
+// lines of removed bars are not included - they are written by the next call to Render (or once
+// rendering ends). It is mostly useful with RefreshManual, where nothing is rendered otherwise
+func (p *Progress) RenderFrame() string {
 	p.mut.Lock()
-	if p.stopped {
+	p.prepare()
+	lines := p.frame()
+	p.rendered()
+	p.mut.Unlock()
+
+	if p.param.Refresh == RefreshManual {
+		p.end()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// changed signals the render loop that a bar has changed (if it isn't already about to render)
+func (p *Progress) changed() {
+	select {
+	case p.changeCh <- struct{}{}:
+	default:
+	}
+}
+
+// detectSize sets the output size, detecting the width and height from the terminal unless given
+func (p *Progress) detectSize() {
+	p.width, p.height = p.param.Width, p.param.Height
+	if p.width == 0 || p.height == 0 {
+		width, height := outputSize(p.param.Out)
+		if p.width == 0 {
+			p.width = width
+		}
+		if p.height == 0 {
+			p.height = height
+		}
+	}
+}
+
+// resize updates the output size after the terminal has been resized
+func (p *Progress) resize() {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	p.detectSize()
+	p.resized = true
+}
+
+// Start begins rendering of the progress bars. Once Wait has returned, Start can be called again
+// to render bars added since, continuing from the previous frame. With RefreshManual, nothing is
+// rendered until the caller calls Render or RenderFrame. Returns ErrStarted if the bars are already
+// being rendered
+func (p *Progress) Start() error {
+	p.mut.Lock()
+	if p.running {
 		p.mut.Unlock()
-		panic("Attempted to start a stopped progess bar")
+		return ErrStarted
 	}
+	p.running = true
+	if p.start.IsZero() {
+		p.start = p.param.Clock.Now()
+	}
+	p.loopDone = make(chan struct{})
 	p.mut.Unlock()
 
+	p.logW.setRendering(true)
+	if p.param.Refresh == RefreshManual {
+		return nil
+	}
 	// Render immediately in case it finishes the moment it starts
-	p.render(false)
+	p.render()
+	clock := p.param.Clock
+	last := clock.Now()
+
+	// Only track resizes when we detected the size ourselves
+	var resizeCh <-chan os.Signal
+	stopResize := func() {}
+	if (p.param.Width == 0 || p.param.Height == 0) && (p.width > 0 || p.height > 0) {
+		resizeCh, stopResize = notifyResize()
+	}
 
 	go func() {
+		defer stopResize()
+		// tick fires once the next frame is due with RefreshInterval, while next fires once the next
+		// frame is due after a change with RefreshOnChange (nil if nothing has changed) and expire
+		// fires once the next finished bar is due to be removed via RemoveAfter
+		var tick, next, expire <-chan time.Time
 		for {
+			switch {
+			case p.param.Refresh == RefreshInterval && tick == nil:
+				tick = clock.After(p.param.Interval)
+			case p.param.Refresh == RefreshOnChange && expire == nil:
+				if d, ok := p.nextRemoval(); ok {
+					expire = clock.After(d)
+				}
+			}
 			select {
-			case <-time.After(p.param.Interval):
-				p.render(true)
-			case <-p.quitCh:
-				close(p.quitCh)
+			case <-tick:
+				tick = nil
+				p.render()
+			case <-p.changeCh:
+				if next == nil {
+					next = clock.After(p.param.Interval - clock.Now().Sub(last))
+				}
+			case <-next:
+				next = nil
+				p.render()
+				last = clock.Now()
+			case <-expire:
+				expire = nil
+				p.render()
+			case <-resizeCh:
+				p.resize()
+				p.render()
+			case <-p.wakeCh:
+			}
+			if p.end() {
 				return
 			}
 		}
 	}()
+	return nil
 }
 
-// Stop stops the render of the progress bars assigning a msg (if not any empty string)
+// end ends the render loop (returning true) once every bar has been rendered for the last time
+// and something is waiting for it
+func (p *Progress) end() bool {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.pending > 0 || p.waiters == 0 {
+		return false
+	}
+	p.running, p.waiters = false, 0
+	p.logW.setRendering(false)
+	close(p.loopDone)
+	return true
+}
+
+// Stop stops all the bars assigning a msg (if not any empty string). Rendering ends once their
+// final frame has been rendered and Wait is called. New bars can still be added afterwards and
+// rendered by calling Start again
 func (p *Progress) Stop(msg, extMsg string) {
 	p.mut.Lock()
 	defer p.mut.Unlock()
 
-	p.stopped = true
 	for _, bar := range p.bars {
 		bar.Stop(msg, extMsg)
 	}
 }
 
-// Wait waits for progress to be finished or cancelled. It can only be called once
-func (p *Progress) Wait() {
-	p.wait.Wait()
-	p.quitCh <- struct{}{}
-	<-p.quitCh
+// cancel cancels all unfinished bars
+func (p *Progress) cancel() {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	for _, bar := range p.bars {
+		bar.Cancel()
+	}
+}
+
+// Wait waits for all bars to be finished, including any added while waiting, and then ends
+// rendering. Any number of calls can wait at the same time. Returns ErrNotStarted if there are
+// unfinished bars but Start has not been called. With RefreshManual, Wait returns once the caller
+// has rendered the final frame of every bar
+func (p *Progress) Wait() error {
+	return p.WaitContext(context.Background())
+}
+
+// WaitContext is like Wait, but also returns once ctx is done. If ctx is done first, all
+// unfinished bars are cancelled and, once their final frame has been rendered (unless using
+// RefreshManual), ctx.Err() is returned
+func (p *Progress) WaitContext(ctx context.Context) error {
+	p.mut.Lock()
+	if !p.running {
+		pending := p.pending
+		p.mut.Unlock()
+		if pending > 0 {
+			return ErrNotStarted
+		}
+		return nil
+	}
+	p.waiters++
+	done := p.loopDone
+	p.mut.Unlock()
+	// Without a render loop, we check ourselves in case the last frame has already been rendered
+	if p.param.Refresh == RefreshManual {
+		p.end()
+	} else {
+		p.wake()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+	p.cancel()
+	// Cancelled bars still need one more render before they are considered done, which is up to
+	// the caller in manual mode
+	if p.param.Refresh != RefreshManual {
+		<-done
+	}
+	return ctx.Err()
+}
+
+// wake wakes the render loop (if it isn't already about to wake)
+func (p *Progress) wake() {
+	select {
+	case p.wakeCh <- struct{}{}:
+	default:
+	}
 }
@@ -0,0 +1,412 @@
+package cmpb_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nu11ptr/cmpb"
+	"github.com/nu11ptr/cmpb/cmpbtest"
+)
+
+func TestMain(m *testing.M) {
+	// The expected output uses Unicode glyphs whatever the terminal and locale running the tests
+	os.Setenv("TERM", "xterm")
+	os.Setenv("LC_ALL", "en_US.UTF-8")
+	os.Exit(m.Run())
+}
+
+// syncBuffer is a bytes.Buffer that can be inspected while a progress is rendering to it
+type syncBuffer struct {
+	mut sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+// newTestProgress creates a progress that renders to out in terminal mode, with any options
+// applied to its params
+func newTestProgress(out io.Writer, options ...func(p *cmpb.Param)) *cmpb.Progress {
+	param := cmpb.DefaultParam()
+	param.Out = out
+	param.Mode = cmpb.ModeTerm
+	param.Interval = time.Millisecond
+	for _, option := range options {
+		option(param)
+	}
+	return cmpb.NewWithParam(param)
+}
+
+// firstFrame returns the lines of the first frame written to out, which is everything before the
+// first scroll up
+func firstFrame(out *bytes.Buffer) []string {
+	first := strings.SplitN(out.String(), "\x1b", 2)[0]
+	return strings.Split(strings.TrimSuffix(first, "\n"), "\n")
+}
+
+func TestWaitContext(t *testing.T) {
+	out := new(bytes.Buffer)
+	p := newTestProgress(out)
+	p.NewBar("stuck", 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	p.Start()
+	if err := p.WaitContext(ctx); err != context.DeadlineExceeded {
+		t.Fatal("want", context.DeadlineExceeded, "got", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	expected := "stuck     : ⊘ cancelled"
+	if last := lines[len(lines)-1]; !strings.Contains(last, expected) {
+		t.Error("want", expected, "got", last)
+	}
+}
+
+func TestBindContext(t *testing.T) {
+	out := new(bytes.Buffer)
+	p := newTestProgress(out)
+	ctx, cancel := context.WithCancel(context.Background())
+	p.NewBar("bound", 10).BindContext(ctx)
+	p.NewBar("free", 10).Update(10)
+
+	p.Start()
+	cancel()
+	p.Wait()
+	if !strings.Contains(out.String(), "bound     : ⊘ cancelled") {
+		t.Error("bar was not cancelled:", out.String())
+	}
+	if state := p.Bar("bound").State(); state != cmpb.StateCancelled {
+		t.Error("want", cmpb.StateCancelled, "got", state)
+	}
+}
+
+func TestLayout(t *testing.T) {
+	tests := []struct {
+		name             string
+		width            int
+		growMsg, growBar bool
+		output           string
+	}{
+		{"Unknown", 0, false, false,
+			"bar       : working...                    0s [=========>----------]  50%"},
+		{"Shrink", 60, false, false,
+			"bar       : worki...          0s [========>----------]  50%"},
+		{"Minimums", 45, false, false,
+			"bar       : worki...          0s [====>-----"},
+		{"GrowBoth", 80, true, true,
+			"bar       : working...                       0s [===========>------------]  50%"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			p := newTestProgress(out, func(p *cmpb.Param) {
+				p.Width, p.GrowMsg, p.GrowBar = test.width, test.growMsg, test.growBar
+			})
+			b := p.NewBar("bar", 10)
+			b.SetMessage("working...")
+			b.Update(5)
+			b.Stop("", "")
+
+			p.Start()
+			p.Wait()
+			output := strings.TrimSuffix(out.String(), "\n")
+			if output != test.output {
+				t.Errorf("want %q got %q", test.output, output)
+			}
+		})
+	}
+}
+
+func TestLineMode(t *testing.T) {
+	out := new(bytes.Buffer)
+	param := cmpb.DefaultParam()
+	param.Out = out
+	param.Interval = time.Millisecond
+	param.LineStep = 25
+	p := cmpb.NewWithParam(param)
+	b := p.NewBar("line", 100)
+
+	p.Start()
+	for i := 0; i < 100; i++ {
+		if i == 60 {
+			b.SetMessage("halfway")
+		}
+		b.Increment()
+		time.Sleep(50 * time.Microsecond)
+	}
+	p.Wait()
+
+	output := out.String()
+	if strings.Contains(output, "\x1b") {
+		t.Errorf("output contains escape codes: %q", output)
+	}
+	// Each line must be printed once, at most once per step plus once for the message change
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > 6 {
+		t.Errorf("too many lines: %q", output)
+	}
+	seen := make(map[string]bool, len(lines))
+	for _, line := range lines {
+		if seen[line] {
+			t.Errorf("duplicate line: %q", line)
+		}
+		seen[line] = true
+	}
+	expected := "line      : halfway                       0s [====================] 100%"
+	if last := lines[len(lines)-1]; last != expected {
+		t.Error("want", expected, "got", last)
+	}
+}
+
+func TestRestart(t *testing.T) {
+	p := newTestProgress(new(bytes.Buffer))
+	first := p.NewBar("first", 10)
+
+	if err := p.Start(); err != nil {
+		t.Fatal("want nil got", err)
+	}
+	if err := p.Start(); err != cmpb.ErrStarted {
+		t.Error("want", cmpb.ErrStarted, "got", err)
+	}
+	first.Update(10)
+	if err := p.Wait(); err != nil {
+		t.Fatal("want nil got", err)
+	}
+
+	second := p.NewBar("second", 10)
+	if err := p.Wait(); err != cmpb.ErrNotStarted {
+		t.Error("want", cmpb.ErrNotStarted, "got", err)
+	}
+	if err := p.Start(); err != nil {
+		t.Fatal("want nil got", err)
+	}
+	second.Update(10)
+	if err := p.Wait(); err != nil {
+		t.Error("want nil got", err)
+	}
+}
+
+func TestAddWhileWaiting(t *testing.T) {
+	p := newTestProgress(new(bytes.Buffer))
+	first := p.NewBar("first", 10)
+	p.Start()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		// Add the next bar before the first finishes so the waiters keep waiting
+		second := p.NewBar("second", 10)
+		first.Update(10)
+		time.Sleep(10 * time.Millisecond)
+		second.Update(10)
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.Wait(); err != nil {
+				t.Error("want nil got", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if state := p.Bar("second").State(); state != cmpb.StateSucceeded {
+		t.Error("want", cmpb.StateSucceeded, "got", state)
+	}
+}
+
+func TestRenderFrame(t *testing.T) {
+	out := new(bytes.Buffer)
+	p := newTestProgress(out, func(p *cmpb.Param) { p.Refresh = cmpb.RefreshManual })
+	first := p.NewBar("first", 10)
+	first.Update(5)
+	p.NewBar("second", 10)
+	p.Start()
+
+	expected := "first     :                               0s [=========>----------]  50%\n" +
+		"second    :                               0s [--------------------]   0%"
+	if frame := p.RenderFrame(); frame != expected {
+		t.Errorf("want %q got %q", expected, frame)
+	}
+	if out.Len() != 0 {
+		t.Errorf("want no output got %q", out.String())
+	}
+
+	done := make(chan error)
+	go func() { done <- p.Wait() }()
+	first.Update(10)
+	p.Bar("second").Update(10)
+	select {
+	case <-done:
+		t.Fatal("returned before the final frame was rendered")
+	case <-time.After(10 * time.Millisecond):
+	}
+	p.Render()
+	if err := <-done; err != nil {
+		t.Error("want nil got", err)
+	}
+	if !strings.HasSuffix(out.String(), "second    :                               0s [====================] 100%\n") {
+		t.Errorf("final frame not written: %q", out.String())
+	}
+}
+
+func TestRefreshOnChange(t *testing.T) {
+	clock := cmpbtest.NewClock(time.Now())
+	p := newTestProgress(new(bytes.Buffer), func(p *cmpb.Param) {
+		p.Refresh, p.Interval, p.Clock = cmpb.RefreshOnChange, time.Second, clock
+	})
+	b := p.NewBar("bar", 100)
+	frames := make(chan int64, 10)
+	b.SetPreBar(func(curr, total int64, start time.Time, stopped bool) string {
+		frames <- curr
+		return ""
+	})
+
+	p.Start()
+	if curr := <-frames; curr != 0 {
+		t.Error("want", 0, "got", curr)
+	}
+	// Lots of changes at once are rendered together once the interval has passed
+	for i := 0; i < 99; i++ {
+		b.Increment()
+	}
+	clock.BlockUntil(1)
+	select {
+	case curr := <-frames:
+		t.Fatal("rendered before the interval passed:", curr)
+	default:
+	}
+	clock.Advance(time.Second)
+	if curr := <-frames; curr != 99 {
+		t.Error("want", 99, "got", curr)
+	}
+
+	b.Increment()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	p.Wait()
+	if len(frames) != 1 {
+		t.Error("want", 1, "got", len(frames))
+	}
+}
+
+// advanceUntil keeps advancing the clock by d until cond is true, since the render loop may not be
+// waiting for its next frame yet (or may need several). Returns false if cond still isn't true
+// after a second
+func advanceUntil(clock *cmpbtest.Clock, d time.Duration, cond func() bool) bool {
+	for i := 0; i < 100; i++ {
+		clock.Advance(d)
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+// waitAdvancing waits for p to finish rendering while advancing the clock by d
+func waitAdvancing(t *testing.T, p *cmpb.Progress, clock *cmpbtest.Clock, d time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		p.Wait()
+		close(done)
+	}()
+	finished := func() bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}
+	if !advanceUntil(clock, d, finished) {
+		t.Fatal("rendering never ended")
+	}
+}
+
+func TestRefreshOnChangeLogs(t *testing.T) {
+	clock := cmpbtest.NewClock(time.Now())
+	out := new(syncBuffer)
+	p := newTestProgress(out, func(p *cmpb.Param) {
+		p.Refresh, p.Interval, p.Clock = cmpb.RefreshOnChange, time.Second, clock
+	})
+	b := p.NewBar("bar", 10)
+
+	p.Start()
+	fmt.Fprintln(p.Writer(), "hello")
+	printed := func() bool { return strings.Contains(string(out.Bytes()), "hello\n") }
+	if !advanceUntil(clock, time.Second, printed) {
+		t.Errorf("log line not printed: %q", out.Bytes())
+	}
+
+	b.Update(10)
+	waitAdvancing(t, p, clock, time.Second)
+}
+
+func TestRefreshOnChangeRemove(t *testing.T) {
+	clock := cmpbtest.NewClock(time.Now())
+	out := new(syncBuffer)
+	p := newTestProgress(out, func(p *cmpb.Param) {
+		p.Refresh, p.Interval, p.Clock = cmpb.RefreshOnChange, time.Second, clock
+		p.RemoveAfter, p.PrintRemoved = time.Hour, true
+	})
+	p.NewBar("done", 10).Update(10)
+	slow := p.NewBar("slow", 10)
+
+	p.Start()
+	// The final line is printed above the bars once the bar is removed
+	expected := "\x1b[Jdone      :                               0s [====================] 100%\n"
+	removed := func() bool { return strings.Contains(string(out.Bytes()), expected) }
+	if !advanceUntil(clock, time.Minute, removed) {
+		t.Errorf("final line not printed: %q", out.Bytes())
+	}
+	if p.Bar("done") != nil {
+		t.Error("finished bar was not removed")
+	}
+
+	slow.Update(10)
+	waitAdvancing(t, p, clock, time.Second)
+}
+
+func TestClockFrames(t *testing.T) {
+	clock := cmpbtest.NewClock(time.Now())
+	out := new(syncBuffer)
+	p := newTestProgress(out, func(p *cmpb.Param) { p.Interval, p.Clock = time.Second, clock })
+	b := p.NewBar("bar", 10)
+
+	p.Start()
+	clock.Frame(90 * time.Second)
+	expected := "bar       :                           1m 30s [--------------------]   0%\n"
+	if !strings.HasSuffix(string(out.Bytes()), expected) {
+		t.Errorf("want suffix %q got %q", expected, out.Bytes())
+	}
+
+	b.Update(10)
+	clock.Frame(time.Second)
+	p.Wait()
+	expected = "bar       :                           1m 31s [====================] 100%\n"
+	if !strings.HasSuffix(string(out.Bytes()), expected) {
+		t.Errorf("want suffix %q got %q", expected, out.Bytes())
+	}
+}
@@ -0,0 +1,127 @@
+package cmpb
+
+import "io"
+
+// ProxyReader returns an io.Reader that adds the number of bytes read from r to the bar. If a read
+// fails with anything other than io.EOF, the bar is marked as failed with the error
+func (b *Bar) ProxyReader(r io.Reader) io.Reader {
+	return &proxyReader{r: r, bar: b}
+}
+
+// ProxyWriter returns an io.Writer that adds the number of bytes written to w to the bar. If a
+// write fails, the bar is marked as failed with the error
+func (b *Bar) ProxyWriter(w io.Writer) io.Writer {
+	return &proxyWriter{w: w, bar: b}
+}
+
+// ProxyReaderAt returns an io.ReaderAt that adds the number of bytes read from r to the bar. If a
+// read fails with anything other than io.EOF, the bar is marked as failed with the error
+func (b *Bar) ProxyReaderAt(r io.ReaderAt) io.ReaderAt {
+	return &proxyReaderAt{r: r, bar: b}
+}
+
+// ProxyWriteCloser returns an io.WriteCloser that adds the number of bytes written to w to the
+// bar. If a write or close fails, the bar is marked as failed with the error
+func (b *Bar) ProxyWriteCloser(w io.WriteCloser) io.WriteCloser {
+	return &proxyWriteCloser{proxyWriter: proxyWriter{w: w, bar: b}, c: w}
+}
+
+// track adds n bytes to the bar and fails it if err is a real error
+func (b *Bar) track(n int64, err error) {
+	if n != 0 {
+		b.Add(n)
+	}
+	if err != nil && err != io.EOF {
+		b.Fail(err)
+	}
+}
+
+// countingReader only exposes Read so io.Copy can't bypass the counting (or recurse back into us)
+type countingReader struct {
+	r   io.Reader
+	bar *Bar
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.bar.track(int64(n), err)
+	return n, err
+}
+
+// countingWriter only exposes Write so io.Copy can't bypass the counting (or recurse back into us)
+type countingWriter struct {
+	w   io.Writer
+	bar *Bar
+}
+
+func (c countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.bar.track(int64(n), err)
+	return n, err
+}
+
+type proxyReader struct {
+	r   io.Reader
+	bar *Bar
+}
+
+func (p *proxyReader) Read(buf []byte) (int, error) {
+	return countingReader{p.r, p.bar}.Read(buf)
+}
+
+// WriteTo lets io.Copy use the underlying reader's WriterTo (if any) while still counting bytes
+func (p *proxyReader) WriteTo(w io.Writer) (int64, error) {
+	if wt, ok := p.r.(io.WriterTo); ok {
+		n, err := wt.WriteTo(countingWriter{w, p.bar})
+		// Writes are already counted, but a failure could also come from the read side
+		if err != nil {
+			p.bar.track(0, err)
+		}
+		return n, err
+	}
+	return io.Copy(w, countingReader{p.r, p.bar})
+}
+
+type proxyWriter struct {
+	w   io.Writer
+	bar *Bar
+}
+
+func (p *proxyWriter) Write(buf []byte) (int, error) {
+	return countingWriter{p.w, p.bar}.Write(buf)
+}
+
+// ReadFrom lets io.Copy use the underlying writer's ReaderFrom (if any) while still counting bytes
+func (p *proxyWriter) ReadFrom(r io.Reader) (int64, error) {
+	if rf, ok := p.w.(io.ReaderFrom); ok {
+		n, err := rf.ReadFrom(countingReader{r, p.bar})
+		// Reads are already counted, but a failure could also come from the write side
+		if err != nil {
+			p.bar.track(0, err)
+		}
+		return n, err
+	}
+	return io.Copy(countingWriter{p.w, p.bar}, r)
+}
+
+type proxyReaderAt struct {
+	r   io.ReaderAt
+	bar *Bar
+}
+
+func (p *proxyReaderAt) ReadAt(buf []byte, off int64) (int, error) {
+	n, err := p.r.ReadAt(buf, off)
+	p.bar.track(int64(n), err)
+	return n, err
+}
+
+type proxyWriteCloser struct {
+	proxyWriter
+	c io.Closer
+}
+
+func (p *proxyWriteCloser) Close() error {
+	err := p.c.Close()
+	p.bar.track(0, err)
+	return err
+}
@@ -0,0 +1,112 @@
+package cmpb_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/nu11ptr/cmpb"
+)
+
+type failReader struct{}
+
+func (failReader) Read(p []byte) (int, error) { return 0, errors.New("disk on fire") }
+
+// failWriterTo fails from WriteTo like *os.File does when reading the file fails
+type failWriterTo struct{ failReader }
+
+func (failWriterTo) WriteTo(w io.Writer) (int64, error) { return 0, errors.New("disk on fire") }
+
+func TestProxyReader(t *testing.T) {
+	data := strings.Repeat("x", 100)
+	tests := []struct {
+		name string
+		r    io.Reader
+	}{
+		{"WriterTo", strings.NewReader(data)},
+		{"Plain", iotest.OneByteReader(strings.NewReader(data))},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := cmpb.New().NewBar("copy", 200)
+			buf := new(bytes.Buffer)
+			if _, err := io.Copy(buf, b.ProxyReader(test.r)); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != data {
+				t.Error("want", data, "got", buf.String())
+			}
+			expected := "copy      :                               0s [=========>----------]  50%"
+			output := b.String()
+			if output != expected {
+				t.Error("want", expected, "got", output)
+			}
+		})
+	}
+}
+
+func TestProxyWriter(t *testing.T) {
+	b := cmpb.New().NewBar("copy", 200)
+	w := b.ProxyWriteCloser(nopWriteCloser{new(bytes.Buffer)})
+	if _, err := io.Copy(w, strings.NewReader(strings.Repeat("x", 150))); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "copy      :                               0s [==============>-----]  75%"
+	output := b.String()
+	if output != expected {
+		t.Error("want", expected, "got", output)
+	}
+}
+
+func TestProxyReaderAt(t *testing.T) {
+	b := cmpb.New().NewBar("read", 100)
+	r := b.ProxyReaderAt(strings.NewReader(strings.Repeat("x", 100)))
+	// Reading past the end returns io.EOF, which must not stop the bar
+	if _, err := r.ReadAt(make([]byte, 60), 50); err != io.EOF {
+		t.Fatal("want", io.EOF, "got", err)
+	}
+	expected := "read      :                               0s [=========>----------]  50%"
+	output := b.String()
+	if output != expected {
+		t.Error("want", expected, "got", output)
+	}
+}
+
+func TestProxyError(t *testing.T) {
+	tests := []struct {
+		name string
+		r    io.Reader
+	}{
+		{"Plain", failReader{}},
+		{"WriterTo", failWriterTo{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := cmpb.New().NewBar("fail", 100)
+			if _, err := io.Copy(ioutil.Discard, b.ProxyReader(test.r)); err == nil {
+				t.Fatal("want error got nil")
+			}
+			expected := "fail      : ✗ failed                      0s [--------------------]   0%"
+			output := b.String()
+			if output != expected {
+				t.Error("want", expected, "got", output)
+			}
+			if err := b.Err(); err == nil || err.Error() != "disk on fire" {
+				t.Error("want disk on fire got", err)
+			}
+		})
+	}
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
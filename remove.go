@@ -0,0 +1,156 @@
+package cmpb
+
+import "time"
+
+// RemoveBar removes the bar with the given key (along with any children) so it is no longer
+// rendered or waited on. If it had already finished, it still counts towards the outcomes of the
+// progress. Returns false if there is no bar with the given key
+func (p *Progress) RemoveBar(key string) bool {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	b, ok := p.barMap[key]
+	if !ok {
+		return false
+	}
+	p.removeBar(b)
+	return true
+}
+
+// SetHidden hides (or shows again) the bar along with any children. Hidden bars are still updated,
+// waited on and included in their parent and the summary, but are not rendered
+func (b *Bar) SetHidden(hidden bool) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if hidden != b.hidden {
+		b.hidden = hidden
+		b.touch()
+	}
+}
+
+func (b *Bar) isHidden() bool {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	return b.hidden
+}
+
+// release detaches a bar being removed, returning true if it was still being waited on
+func (b *Bar) release() bool {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	// Anything not yet rendered for the last time has yet to be marked as done
+	pending := !b.stopped || b.lastRender
+	b.lastRender = false
+	return pending
+}
+
+func (p *Progress) removeBar(b *Bar) {
+	removed := make(map[*Bar]bool)
+	var walk func(*Bar)
+	walk = func(b *Bar) {
+		removed[b] = true
+		for _, child := range b.childBars() {
+			walk(child)
+		}
+	}
+	walk(b)
+
+	if parent := b.parent; parent != nil {
+		parent.mut.Lock()
+		children := make([]*Bar, 0, len(parent.children))
+		for _, child := range parent.children {
+			if child != b {
+				children = append(children, child)
+			}
+		}
+		parent.children = children
+		parent.mut.Unlock()
+	}
+
+	bars := make([]*Bar, 0, len(p.bars))
+	for _, bar := range p.bars {
+		if !removed[bar] {
+			bars = append(bars, bar)
+			continue
+		}
+		if p.barMap[bar.key] == bar {
+			delete(p.barMap, bar.key)
+		}
+		delete(p.lines, bar)
+		if bar.release() {
+			p.pending--
+		}
+		// Only finished bars have an outcome worth keeping
+		if state := bar.State(); state != StateRunning {
+			p.removed[state]++
+		}
+	}
+	p.bars = bars
+}
+
+// removeFinished removes the top level bars that have been finished for at least RemoveAfter,
+// queuing their final line to be printed above the bars if requested
+func (p *Progress) removeFinished() {
+	if p.param.RemoveAfter <= 0 {
+		return
+	}
+	now := p.param.Clock.Now()
+	var expired []*Bar
+	for _, b := range p.bars {
+		if b.parent != nil {
+			continue
+		}
+		// Bars are only removed once their final frame has been rendered
+		if info := b.info(); info.stopped && !info.lastRender &&
+			now.Sub(info.finished) >= p.param.RemoveAfter {
+			expired = append(expired, b)
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+
+	l := p.param.layout(p.width)
+	for _, b := range expired {
+		// In line mode, the final line was already printed when the bar finished
+		if p.param.PrintRemoved && p.lines == nil {
+			p.removedLines = append(p.removedLines, p.fitLine(b.render(l)))
+		}
+		p.removeBar(b)
+	}
+}
+
+// nextRemoval returns how long until the next top level bar is due to be removed via RemoveAfter,
+// or false if none are. Bars yet to render their final frame are left out since they are about to
+// render anyway
+func (p *Progress) nextRemoval() (time.Duration, bool) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.param.RemoveAfter <= 0 {
+		return 0, false
+	}
+	now := p.param.Clock.Now()
+	var next time.Duration
+	found := false
+	for _, b := range p.bars {
+		if b.parent != nil {
+			continue
+		}
+		if info := b.info(); info.stopped && !info.lastRender {
+			if d := p.param.RemoveAfter - now.Sub(info.finished); !found || d < next {
+				next, found = d, true
+			}
+		}
+	}
+	return next, found
+}
+
+func (p *Progress) takeRemovedLines() []string {
+	lines := p.removedLines
+	p.removedLines = nil
+	return lines
+}
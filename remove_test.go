@@ -0,0 +1,84 @@
+package cmpb_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nu11ptr/cmpb"
+)
+
+func TestRemoveBar(t *testing.T) {
+	out := new(bytes.Buffer)
+	p := newTestProgress(out)
+	p.NewBar("keep", 10)
+	parent := p.NewBar("gone", 0)
+	p.NewChildBar(parent, "child", 10)
+
+	if p.RemoveBar("missing") {
+		t.Error("removed a bar that doesn't exist")
+	}
+	if !p.RemoveBar("gone") {
+		t.Fatal("failed to remove bar")
+	}
+	if p.Bar("gone") != nil || p.Bar("child") != nil {
+		t.Error("removed bars can still be found")
+	}
+
+	// The removed bars never finish, so this only returns if they are no longer waited on
+	p.Start()
+	p.Bar("keep").Update(10)
+	p.Wait()
+
+	first := strings.Join(firstFrame(out), "\n")
+	if strings.Contains(first, "gone") || strings.Contains(first, "child") {
+		t.Error("removed bars were rendered:", first)
+	}
+	// Unfinished bars are forgotten once removed
+	if running := p.Outcomes()[cmpb.StateRunning]; running != 0 {
+		t.Error("want", 0, "got", running)
+	}
+}
+
+func TestAutoRemove(t *testing.T) {
+	out := new(syncBuffer)
+	p := newTestProgress(out, func(p *cmpb.Param) {
+		p.RemoveAfter, p.PrintRemoved = time.Millisecond, true
+	})
+	p.NewBar("done", 10).Update(10)
+	slow := p.NewBar("slow", 10)
+
+	p.Start()
+	time.Sleep(20 * time.Millisecond)
+	slow.Update(10)
+	p.Wait()
+
+	if p.Bar("done") != nil {
+		t.Error("finished bar was not removed")
+	}
+	if done := p.Outcomes()[cmpb.StateSucceeded]; done != 2 {
+		t.Error("want", 2, "got", done)
+	}
+	// The final line is printed above the bars once the old frame is cleared
+	expected := "\x1b[Jdone      :                               0s [====================] 100%\n"
+	if !strings.Contains(string(out.Bytes()), expected) {
+		t.Errorf("final line not printed: %q", out.Bytes())
+	}
+}
+
+func TestHidden(t *testing.T) {
+	out := new(bytes.Buffer)
+	p := newTestProgress(out)
+	p.NewBar("shown", 10).Update(10)
+	hidden := p.NewBar("hidden", 10)
+	hidden.SetHidden(true)
+
+	p.Start()
+	hidden.Update(10)
+	p.Wait()
+
+	if strings.Contains(out.String(), "hidden") {
+		t.Error("hidden bar was rendered:", out.String())
+	}
+}
@@ -0,0 +1,26 @@
+//go:build go1.21
+// +build go1.21
+
+package cmpb
+
+import (
+	"log"
+	"log/slog"
+)
+
+// SlogHandler returns a slog text handler that writes to Writer
+func (p *Progress) SlogHandler(opts *slog.HandlerOptions) slog.Handler {
+	return slog.NewTextHandler(p.logW, opts)
+}
+
+// RedirectSlog makes a logger using SlogHandler the default slog logger (which also captures the
+// standard log package). The returned function restores the previous defaults
+func (p *Progress) RedirectSlog(opts *slog.HandlerOptions) (restore func()) {
+	prev, out, flags := slog.Default(), log.Writer(), log.Flags()
+	slog.SetDefault(slog.New(p.SlogHandler(opts)))
+	return func() {
+		slog.SetDefault(prev)
+		log.SetOutput(out)
+		log.SetFlags(flags)
+	}
+}
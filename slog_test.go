@@ -0,0 +1,23 @@
+//go:build go1.21
+// +build go1.21
+
+package cmpb_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedirectSlog(t *testing.T) {
+	out := new(bytes.Buffer)
+	p := newTestProgress(out)
+	restore := p.RedirectSlog(nil)
+	defer restore()
+
+	slog.Info("redirected", "key", "value")
+	if !strings.Contains(out.String(), "msg=redirected key=value\n") {
+		t.Errorf("log line missing: %q", out.String())
+	}
+}
@@ -0,0 +1,205 @@
+package cmpb
+
+import "unicode"
+
+// State represents the outcome of a bar
+type State int
+
+const (
+	// StateRunning is the state of a bar that has not yet finished
+	StateRunning State = iota
+	// StateSucceeded is the state of a bar that reached its total or was marked via Succeed (only
+	// the latter shows SucceedMsg)
+	StateSucceeded
+	// StateFailed is the state of a bar marked via Fail
+	StateFailed
+	// StateSkipped is the state of a bar marked via Skip
+	StateSkipped
+	// StateCancelled is the state of a bar marked via Cancel (or whose context was done)
+	StateCancelled
+	// StateStopped is the state of a bar stopped via Stop without a specific outcome
+	StateStopped
+)
+
+// states lists every state in the order they are reported
+var states = []State{
+	StateRunning, StateSucceeded, StateFailed, StateSkipped, StateCancelled, StateStopped,
+}
+
+func (s State) String() string {
+	switch s {
+	case StateRunning:
+		return "running"
+	case StateSucceeded:
+		return "done"
+	case StateFailed:
+		return "failed"
+	case StateSkipped:
+		return "skipped"
+	case StateCancelled:
+		return "cancelled"
+	case StateStopped:
+		return "stopped"
+	}
+	return "unknown"
+}
+
+// stateColor returns the color used for the message and bar of a finished bar in the given state
+func (b *BarColors) stateColor(s State) func(string, ...interface{}) string {
+	switch s {
+	case StateSucceeded:
+		return b.Succeeded
+	case StateFailed:
+		return b.Failed
+	case StateSkipped:
+		return b.Skipped
+	case StateCancelled:
+		return b.Cancelled
+	}
+	return nil
+}
+
+// stateMsg returns the default message of the given state prefixed by its glyph (if any)
+func (p *Param) stateMsg(s State) string {
+	var glyph rune
+	var msg string
+	switch s {
+	case StateSucceeded:
+		glyph, msg = p.SucceedGlyph, p.SucceedMsg
+	case StateFailed:
+		glyph, msg = p.FailGlyph, p.FailMsg
+	case StateSkipped:
+		glyph, msg = p.SkipGlyph, p.SkipMsg
+	case StateCancelled:
+		glyph, msg = p.CancelGlyph, p.CancelMsg
+	}
+	switch {
+	case glyph == 0:
+		return msg
+	case msg == "":
+		return string(glyph)
+	}
+	return string(glyph) + " " + msg
+}
+
+// asciiGlyphs leaves out any glyphs that aren't ASCII, for terminals that can't display Unicode
+func (p *Param) asciiGlyphs() {
+	for _, glyph := range []*rune{&p.SucceedGlyph, &p.FailGlyph, &p.SkipGlyph, &p.CancelGlyph} {
+		if *glyph > unicode.MaxASCII {
+			*glyph = 0
+		}
+	}
+}
+
+// end finishes the bar with the given state and final messages (if not empty strings)
+func (b *Bar) end(state State, msg, extMsg string) {
+	if b.stopped {
+		return
+	}
+	b.finish(state)
+
+	msgColor, extColor := b.colors.StopMsg, b.colors.StopExtMsg
+	if c := b.colors.stateColor(state); c != nil {
+		msgColor, extColor = c, c
+	}
+	if msg != "" {
+		b.msg = msgColor(msg)
+	}
+	if extMsg != "" {
+		b.extMsg = extColor(extMsg)
+		// Only prepend the key name when rendered below the bars
+		if !b.p.InlineExtMsg {
+			b.extMsg = b.colors.Key(b.key) + b.colors.KeyDiv(": ") + b.extMsg
+		}
+	}
+}
+
+// Succeed marks the bar as successfully finished, filling it and showing the success message from
+// the progress parameters
+func (b *Bar) Succeed() {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if !b.stopped && b.total > 0 && b.curr != b.total {
+		b.curr = b.total
+	}
+	b.end(StateSucceeded, b.p.stateMsg(StateSucceeded), "")
+}
+
+// Fail marks the bar as failed, showing the failure message from the progress parameters. If err
+// is not nil, it is shown as the extended message and can be retrieved later via Err
+func (b *Bar) Fail(err error) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if b.stopped {
+		return
+	}
+	extMsg := ""
+	if err != nil {
+		extMsg = err.Error()
+	}
+	b.err = err
+	b.end(StateFailed, b.p.stateMsg(StateFailed), extMsg)
+}
+
+// Skip marks the bar as skipped, showing the skip message from the progress parameters
+func (b *Bar) Skip() {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.end(StateSkipped, b.p.stateMsg(StateSkipped), "")
+}
+
+// Cancel marks the bar as cancelled, showing the cancel message from the progress parameters
+func (b *Bar) Cancel() {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.end(StateCancelled, b.p.stateMsg(StateCancelled), "")
+}
+
+// State returns the current state of the bar
+func (b *Bar) State() State {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	return b.state
+}
+
+// Err returns the error the bar failed with (if any)
+func (b *Bar) Err() error {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	return b.err
+}
+
+// Outcomes returns how many bars are in each state, including any finished bars that have been
+// removed
+func (p *Progress) Outcomes() map[State]int {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	return p.outcomes()
+}
+
+func (p *Progress) outcomes() map[State]int {
+	counts := make(map[State]int, len(states))
+	for s, n := range p.removed {
+		counts[s] = n
+	}
+	for _, bar := range p.bars {
+		counts[bar.State()]++
+	}
+	return counts
+}
+
+// fmtOutcomes formats the number of bars in each state, leaving out any that are zero
+func fmtOutcomes(counts map[State]int) string {
+	list := make([]count, len(states))
+	for i, s := range states {
+		list[i] = count{counts[s], s.String()}
+	}
+	return fmtCounts(list...)
+}
@@ -0,0 +1,148 @@
+package cmpb_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nu11ptr/cmpb"
+)
+
+func TestOutcomes(t *testing.T) {
+	tests := []struct {
+		name   string
+		end    func(b *cmpb.Bar)
+		state  cmpb.State
+		output string
+	}{
+		{"Succeed", func(b *cmpb.Bar) { b.Succeed() }, cmpb.StateSucceeded,
+			"task      : ✓ done                        0s [====================] 100%"},
+		{"Fail", func(b *cmpb.Bar) { b.Fail(errors.New("boom")) }, cmpb.StateFailed,
+			"task      : ✗ failed                      0s [====>---------------]  25%"},
+		{"Skip", func(b *cmpb.Bar) { b.Skip() }, cmpb.StateSkipped,
+			"task      : ↷ skipped                     0s [====>---------------]  25%"},
+		{"Cancel", func(b *cmpb.Bar) { b.Cancel() }, cmpb.StateCancelled,
+			"task      : ⊘ cancelled                   0s [====>---------------]  25%"},
+		{"Stop", func(b *cmpb.Bar) { b.Stop("halt", "") }, cmpb.StateStopped,
+			"task      : halt                          0s [====>---------------]  25%"},
+		// Reaching the total succeeds without replacing the message
+		{"Complete", func(b *cmpb.Bar) { b.Update(100) }, cmpb.StateSucceeded,
+			"task      :                               0s [====================] 100%"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := cmpb.New().NewBar("task", 100)
+			b.Update(25)
+			if state := b.State(); state != cmpb.StateRunning {
+				t.Error("want", cmpb.StateRunning, "got", state)
+			}
+			test.end(b)
+			// Only the first outcome counts
+			b.Fail(errors.New("too late"))
+			if state := b.State(); state != test.state {
+				t.Error("want", test.state, "got", state)
+			}
+			if output := b.String(); output != test.output {
+				t.Error("want", test.output, "got", output)
+			}
+		})
+	}
+}
+
+func TestASCIIGlyphs(t *testing.T) {
+	defer setenv(map[string]string{"TERM": "linux", "LC_ALL": "en_US.UTF-8"})()
+
+	param := cmpb.DefaultParam()
+	param.SkipGlyph = '-'
+	p := cmpb.NewWithParam(param)
+	succeeded, skipped := p.NewBar("succeeded", 10), p.NewBar("skipped", 10)
+	succeeded.Succeed()
+	skipped.Skip()
+
+	// Only the glyphs the terminal can't display are left out
+	expected := "succeeded : done                          0s [====================] 100%"
+	if output := succeeded.String(); output != expected {
+		t.Error("want", expected, "got", output)
+	}
+	expected = "skipped   : - skipped                     0s [--------------------]   0%"
+	if output := skipped.String(); output != expected {
+		t.Error("want", expected, "got", output)
+	}
+}
+
+func TestFailErr(t *testing.T) {
+	param := cmpb.DefaultParam()
+	param.InlineExtMsg = true
+	b := cmpb.NewWithParam(param).NewBar("task", 100)
+	err := errors.New("boom")
+	b.Fail(err)
+	if b.Err() != err {
+		t.Error("want", err, "got", b.Err())
+	}
+}
+
+func TestStateColors(t *testing.T) {
+	colors := cmpb.DefaultColors()
+	colors.Failed = func(s string, _ ...interface{}) string { return "<" + s + ">" }
+	param := cmpb.DefaultParam()
+	param.FailGlyph = 0
+	b := cmpb.NewWithParam(param).NewBar("task", 10)
+	b.SetColors(colors)
+	b.Update(5)
+	b.Fail(nil)
+
+	expected := "task      : <failed>                      0s [<=========><>>----------]  50%"
+	if output := b.String(); output != expected {
+		t.Error("want", expected, "got", output)
+	}
+}
+
+func TestFinishedKeepsFillColor(t *testing.T) {
+	defer forceColor()()
+
+	green := func(s string, _ ...interface{}) string { return "<" + s + ">" }
+	tests := []struct {
+		name   string
+		set    func(c *cmpb.BarColors)
+		output string
+	}{
+		{"Full", func(c *cmpb.BarColors) { c.Full = green }, "[<====================>]"},
+		{"Fill", func(c *cmpb.BarColors) { c.Fill = cmpb.Gradient(cmpb.RGB{R: 0, G: 255, B: 0}) },
+			"[\x1b[38;2;0;255;0m====================\x1b[0m]"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			param := cmpb.DefaultParam()
+			param.ColorDepth = cmpb.ColorTrue
+			b := cmpb.NewWithParam(param).NewBar("task", 10)
+			colors := cmpb.DefaultColors()
+			test.set(colors)
+			b.SetColors(colors)
+			b.Update(10)
+
+			if output := b.String(); !strings.Contains(output, test.output) {
+				t.Errorf("want %q in %q", test.output, output)
+			}
+		})
+	}
+}
+
+func TestProgressOutcomes(t *testing.T) {
+	p := cmpb.New()
+	p.NewBar("running", 10)
+	p.NewBar("done", 10).Update(10)
+	p.NewBar("failed1", 10).Fail(nil)
+	p.NewBar("failed2", 10).Fail(nil)
+	p.NewBar("skipped", 10).Skip()
+
+	expected := map[cmpb.State]int{
+		cmpb.StateRunning: 1, cmpb.StateSucceeded: 1, cmpb.StateFailed: 2, cmpb.StateSkipped: 1,
+	}
+	outcomes := p.Outcomes()
+	if fmt.Sprint(outcomes) != fmt.Sprint(expected) {
+		t.Error("want", expected, "got", outcomes)
+	}
+}
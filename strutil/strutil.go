@@ -110,10 +110,12 @@ func ResizeR(s, post string, l int) string {
 }
 
 func resize(s, post string, l int, padRight bool) string {
-	postLen := Len(post)
-	if postLen > l {
-		panic("Post string must be shorter than desired length")
+	if l < 0 {
+		l = 0
 	}
+	// If even the post string doesn't fit, it is truncated too
+	post, _ = Truncate(post, l)
+	postLen := Len(post)
 
 	sLen := Len(s)
 	// Perfect length, return as is
@@ -65,6 +65,8 @@ func TestResize(t *testing.T) {
 		{"TooShortL", color.HiCyanString("abc"), "   " + color.HiCyanString("abc"), post, 6, strutil.ResizeL},
 		{"TooShortR", color.HiCyanString("abc"), color.HiCyanString("abc") + "   ", post, 6, strutil.ResizeR},
 		{"TooLong", color.HiCyanString("abcabcabc"), color.HiCyanString("abc") + post, post, 6, strutil.ResizeL},
+		{"PostTooLong", "abcabc", "..", "...", 2, strutil.ResizeR},
+		{"Negative", "abc", "", "...", -1, strutil.ResizeR},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,61 @@
+package strutil
+
+import (
+	"math"
+	"strconv"
+)
+
+// Units represents a system of unit prefixes used when formatting sizes
+type Units int
+
+const (
+	// IEC uses binary prefixes that are powers of 1024 (KiB, MiB, GiB, ...)
+	IEC Units = iota
+	// SI uses decimal prefixes that are powers of 1000 (kB, MB, GB, ...)
+	SI
+)
+
+var (
+	iecPrefixes   = []string{"Ki", "Mi", "Gi", "Ti", "Pi", "Ei"}
+	siPrefixes    = []string{"k", "M", "G", "T", "P", "E"}
+	countPrefixes = []string{"k", "M", "G", "T", "P", "E"}
+)
+
+// FmtBytes formats a number of bytes using the given units and number of digits after the decimal
+// point (e.g. "12.3 MiB" or "4.5 MB"). Values under 1 KiB/kB are always shown as whole bytes
+func FmtBytes(n int64, units Units, prec int) string {
+	if units == SI {
+		return fmtUnits(n, 1000, siPrefixes, prec, " ") + "B"
+	}
+	return fmtUnits(n, 1024, iecPrefixes, prec, " ") + "B"
+}
+
+// FmtCount formats a generic count using k/M/G/... suffixes (powers of 1000) and the given number
+// of digits after the decimal point (e.g. "4.5k"). Values under 1000 are shown as is
+func FmtCount(n int64, prec int) string {
+	return fmtUnits(n, 1000, countPrefixes, prec, "")
+}
+
+func fmtUnits(n int64, base float64, prefixes []string, prec int, sep string) string {
+	val := math.Abs(float64(n))
+	if val < base {
+		return strconv.FormatInt(n, 10) + sep
+	}
+
+	i := -1
+	for val >= base && i < len(prefixes)-1 {
+		val /= base
+		i++
+	}
+	s := strconv.FormatFloat(val, 'f', prec, 64)
+	// Rounding can carry us up to the next prefix (e.g. 1023.99 KiB would be shown as "1024.0 KiB")
+	if rounded, _ := strconv.ParseFloat(s, 64); rounded >= base && i < len(prefixes)-1 {
+		val /= base
+		i++
+		s = strconv.FormatFloat(val, 'f', prec, 64)
+	}
+	if n < 0 {
+		s = "-" + s
+	}
+	return s + sep + prefixes[i]
+}
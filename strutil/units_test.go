@@ -0,0 +1,52 @@
+package strutil_test
+
+import (
+	"testing"
+
+	"github.com/nu11ptr/cmpb/strutil"
+)
+
+func TestFmtBytes(t *testing.T) {
+	tests := []struct {
+		name, output string
+		input        int64
+		units        strutil.Units
+		prec         int
+	}{
+		{"Bytes", "512 B", 512, strutil.IEC, 1},
+		{"IEC", "12.3 MiB", 12*1024*1024 + 300*1024, strutil.IEC, 1},
+		{"SI", "4.50 MB", 4500000, strutil.SI, 2},
+		{"Carry", "1.0 GiB", 1024*1024*1024 - 1, strutil.IEC, 1},
+		{"Negative", "-2 kB", -2000, strutil.SI, 0},
+		{"Max", "8.0 EiB", 1<<63 - 1, strutil.IEC, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := strutil.FmtBytes(test.input, test.units, test.prec)
+			if output != test.output {
+				t.Error("want", test.output, "got", output)
+			}
+		})
+	}
+}
+
+func TestFmtCount(t *testing.T) {
+	tests := []struct {
+		name, output string
+		input        int64
+	}{
+		{"Small", "999", 999},
+		{"Kilo", "4.5k", 4500},
+		{"Giga", "1.2G", 1200000000},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := strutil.FmtCount(test.input, 1)
+			if output != test.output {
+				t.Error("want", test.output, "got", output)
+			}
+		})
+	}
+}
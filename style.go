@@ -0,0 +1,168 @@
+package cmpb
+
+import (
+	"strings"
+
+	"github.com/nu11ptr/cmpb/strutil"
+)
+
+// BarStyle decides which characters a bar is drawn with
+type BarStyle interface {
+	// Brackets returns the left and right brackets drawn around the bar
+	Brackets() (left, right string)
+	// Fill returns the filled cells, the leading cell(s) and the empty cells of a bar width
+	// characters wide (not including the brackets) at curr out of total (which is positive). The
+	// lengths of the three, ignoring ANSI escape codes, must add up to width
+	Fill(curr, total int64, width int) (full, head, empty string)
+}
+
+// Style is a BarStyle built from repeated segments. Each segment may be several characters long
+// and contain ANSI escape codes, in which case it is repeated and then cut to fit the space
+// available
+type Style struct {
+	LBracket, RBracket, Full, Curr, Empty string
+	// Partial (if not empty) is drawn in the leading cell to show how full it is, from the least
+	// to the most full (such as eighths of a block). Curr is not used when set
+	Partial []string
+	// Fallback (if set) is used instead when the terminal or locale can't display Unicode
+	Fallback BarStyle
+}
+
+// Brackets returns the left and right brackets
+func (s *Style) Brackets() (left, right string) {
+	return s.LBracket, s.RBracket
+}
+
+// Fill returns the filled, leading and empty cells of the bar
+func (s *Style) Fill(curr, total int64, width int) (full, head, empty string) {
+	if width <= 0 {
+		return "", "", ""
+	}
+	if len(s.Partial) > 0 {
+		steps := int64(len(s.Partial)) + 1
+		// The current status can be outside the total while the total is open
+		units := scale(curr, total, int64(width)*steps)
+		if units < 0 {
+			units = 0
+		} else if limit := int64(width) * steps; units > limit {
+			units = limit
+		}
+		fullW, emptyW := int(units/steps), width-int(units/steps)
+		if part := units % steps; part > 0 {
+			head = repeat(s.Partial[part-1], 1)
+			emptyW--
+		}
+		return repeat(s.Full, fullW), head, repeat(s.Empty, emptyW)
+	}
+
+	fullW := int(scale(curr, total, int64(width)))
+	if fullW < 0 {
+		fullW = 0
+	} else if fullW > width {
+		fullW = width
+	}
+	emptyW := width - fullW
+	// The head is only drawn while the bar is incomplete and once there is room for all of it
+	if headW := strutil.Len(s.Curr); emptyW > 0 && fullW > 0 && headW <= fullW {
+		fullW -= headW
+		head = s.Curr
+	}
+	return repeat(s.Full, fullW), head, repeat(s.Empty, emptyW)
+}
+
+// repeat repeats seg until it is exactly width characters long (ignoring ANSI escape codes)
+func repeat(seg string, width int) string {
+	n := strutil.Len(seg)
+	if n == 0 || width <= 0 {
+		return ""
+	}
+	s, _ := strutil.Truncate(strings.Repeat(seg, (width+n-1)/n), width)
+	return s
+}
+
+// ClassicStyle returns the default style: [===>----]
+func ClassicStyle() *Style {
+	return runeStyle(defaultLBracket, defaultRBracket, defaultFull, defaultCurr, defaultEmpty)
+}
+
+// smoothBlocks are the partial blocks from one to seven eighths of a character
+var smoothBlocks = strings.Split("▏▎▍▌▋▊▉", "")
+
+const smoothFull, smoothEmpty = '█', ' '
+
+// BlocksStyle returns a style of solid blocks that uses eighths of a block to show partial
+// progress: │███▌    │
+func BlocksStyle() *Style {
+	return &Style{
+		LBracket: "│", RBracket: "│", Full: string(smoothFull), Empty: string(smoothEmpty),
+		Partial: smoothBlocks, Fallback: ClassicStyle(),
+	}
+}
+
+// DotsStyle returns a style of dots: ●●●●○○○○
+func DotsStyle() *Style {
+	return &Style{
+		Full: "●", Curr: "●", Empty: "○",
+		Fallback: &Style{LBracket: "[", RBracket: "]", Full: "*", Curr: "*", Empty: "."},
+	}
+}
+
+// BrailleStyle returns a style of braille patterns that fills each cell one dot at a time:
+// ⣿⣿⣿⣧⣀⣀⣀⣀
+func BrailleStyle() *Style {
+	return &Style{
+		Full: "⣿", Empty: "⣀", Partial: strings.Split("⡀⡄⡆⡇⣇⣧⣷", ""),
+		Fallback: &Style{LBracket: "[", RBracket: "]", Full: "#", Curr: "#", Empty: "."},
+	}
+}
+
+// ArrowStyle returns a style with a long arrow head: |----->    |
+func ArrowStyle() *Style {
+	return &Style{LBracket: "|", RBracket: "|", Full: "-", Curr: "->", Empty: " "}
+}
+
+// ThinStyle returns a style of a thin line that thickens as it fills: ━━━━╸───
+func ThinStyle() *Style {
+	return &Style{
+		Full: "━", Curr: "╸", Empty: "─",
+		Fallback: &Style{LBracket: "[", RBracket: "]", Full: "-", Curr: "-", Empty: " "},
+	}
+}
+
+func runeStyle(lBracket, rBracket, full, curr, empty rune) *Style {
+	return &Style{
+		LBracket: string(lBracket), RBracket: string(rBracket), Full: string(full), Curr: string(curr),
+		Empty: string(empty),
+	}
+}
+
+// style returns the style given by the param: Style if set, otherwise one built from its runes
+func (p *Param) style() BarStyle {
+	if p.Style != nil {
+		return fallback(p.Style)
+	}
+	s := runeStyle(p.LBracket, p.RBracket, p.Full, p.Curr, p.Empty)
+	if p.Smooth {
+		s.Full, s.Empty, s.Partial = string(smoothFull), string(smoothEmpty), smoothBlocks
+	}
+	return s
+}
+
+// fallback returns the fallback of style if it has one and the terminal can't display Unicode
+func fallback(style BarStyle) BarStyle {
+	if s, ok := style.(*Style); ok && s.Fallback != nil && !unicodeSupported() {
+		return fallback(s.Fallback)
+	}
+	return style
+}
+
+// SetStyle sets the style used to draw the bar. If nil, the style of the progress is used
+func (b *Bar) SetStyle(style BarStyle) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if style != nil {
+		style = fallback(style)
+	}
+	b.style = style
+}
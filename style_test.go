@@ -0,0 +1,84 @@
+package cmpb_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/nu11ptr/cmpb"
+)
+
+func TestStyles(t *testing.T) {
+	defer setenv(map[string]string{"TERM": "xterm", "LC_ALL": "en_US.UTF-8"})()
+
+	tests := []struct {
+		name   string
+		style  *cmpb.Style
+		curr   int64
+		output string
+	}{
+		{"Classic", cmpb.ClassicStyle(), 35, "[==>-------]"},
+		{"Blocks", cmpb.BlocksStyle(), 35, "│███▌      │"},
+		{"Dots", cmpb.DotsStyle(), 35, "●●●●○○○○○○○○"},
+		{"Braille", cmpb.BrailleStyle(), 35, "⣿⣿⣿⣿⡀⣀⣀⣀⣀⣀⣀⣀"},
+		{"Arrow", cmpb.ArrowStyle(), 35, "|-->       |"},
+		{"ArrowStart", cmpb.ArrowStyle(), 10, "|-         |"},
+		{"Thin", cmpb.ThinStyle(), 35, "━━━╸────────"},
+		{"Done", cmpb.ThinStyle(), 100, "━━━━━━━━━━━━"},
+		{"MultiRune", &cmpb.Style{LBracket: "<<", RBracket: ">>", Full: "=-", Empty: ". "}, 50,
+			"<<=-=-. . >>"},
+		{"Ansi", &cmpb.Style{Full: "\x1b[1m#\x1b[0m", Empty: "."}, 25,
+			"\x1b[1m#\x1b[0m\x1b[1m#\x1b[0m\x1b[1m#\x1b[0m........."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			param := cmpb.DefaultParam()
+			param.BarWidth = 12
+			b := cmpb.NewWithParam(param).NewBar("style", 100)
+			b.SetStyle(test.style)
+			b.Update(test.curr)
+			if output := b.String(); !strings.Contains(output, test.output) {
+				t.Errorf("want %q got %q", test.output, output)
+			}
+		})
+	}
+}
+
+func TestStyleFallback(t *testing.T) {
+	defer setenv(map[string]string{"TERM": "xterm", "LC_ALL": "C"})()
+
+	param := cmpb.DefaultParam()
+	param.BarWidth, param.Style = 12, cmpb.BrailleStyle()
+	p := cmpb.NewWithParam(param)
+	b1, b2 := p.NewBar("b1", 10), p.NewBar("b2", 10)
+	b2.SetStyle(cmpb.BlocksStyle())
+	b1.Update(5)
+	b2.Update(5)
+
+	for _, test := range []struct {
+		bar    *cmpb.Bar
+		output string
+	}{{b1, "[#####.....]"}, {b2, "[====>-----]"}} {
+		if output := test.bar.String(); !strings.Contains(output, test.output) {
+			t.Error("want", test.output, "got", output)
+		}
+	}
+}
+
+func TestStyleColors(t *testing.T) {
+	defer forceColor()()
+
+	b := cmpb.New().NewBar("colors", 10)
+	colors := cmpb.DefaultColors()
+	colors.Full = color.New(color.FgGreen).SprintfFunc()
+	b.SetColors(colors)
+	b.SetStyle(&cmpb.Style{LBracket: "[", RBracket: "]", Full: "#", Empty: " "})
+	b.Update(5)
+
+	// Multiple characters are colored as one segment
+	want := "[" + color.New(color.FgGreen).Sprint("##########") + strings.Repeat(" ", 10) + "]"
+	if output := b.String(); !strings.Contains(output, want) {
+		t.Errorf("want %q got %q", want, output)
+	}
+}
@@ -0,0 +1,84 @@
+package cmpb
+
+import "time"
+
+// summaryWindow is how much history is used to estimate the time remaining for all bars
+const summaryWindow = 10 * time.Second
+
+// SummaryPosition represents where the summary bar is pinned
+type SummaryPosition int
+
+const (
+	// SummaryTop pins the summary bar above all other bars
+	SummaryTop SummaryPosition = iota
+	// SummaryBottom pins the summary bar below all other bars
+	SummaryBottom
+)
+
+// ShowSummary adds a summary bar with the given key, pinned at the given position. It shows the
+// combined progress of all bars, how many bars are in each state (including finished bars since
+// removed) as its message and the estimated time remaining as its prebar. The summary bar is
+// returned so its colors and decorators can be changed, but its status and message are set
+// automatically. Calling ShowSummary again changes the key and position of the existing summary
+// bar
+func (p *Progress) ShowSummary(key string, pos SummaryPosition) *Bar {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.summary == nil {
+		p.summary = newBar(key, 0, &p.param)
+		eta := CalcETA(func() Estimator { return NewWindowEstimator(summaryWindow) })(p.param.Clock)
+		p.summary.preBarF = func(curr, total int64, start time.Time, stopped bool) string {
+			return "ETA " + eta(curr, total, start, stopped)
+		}
+	}
+	p.summary.mut.Lock()
+	p.summary.key = key
+	p.summary.mut.Unlock()
+	p.summaryPos = pos
+	return p.summary
+}
+
+// updateSummary recalculates the summary bar (if any) from all the other bars
+func (p *Progress) updateSummary() {
+	if p.summary == nil {
+		return
+	}
+
+	var curr, total int64
+	counts := p.outcomes()
+	for _, b := range p.bars {
+		info := b.info()
+		// Children are already included in their parents
+		if b.parent == nil && info.total > 0 {
+			curr += minInt64(info.curr, info.total)
+			total += info.total
+		}
+	}
+
+	s := p.summary
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if !p.start.IsZero() {
+		s.start = p.start
+	}
+	finished := counts[StateRunning] == 0 && len(counts) > 0
+	if curr != s.curr || finished != s.stopped {
+		s.changed = p.param.Clock.Now()
+	}
+	s.curr, s.total, s.stopped = curr, total, finished
+	s.msg = fmtOutcomes(counts)
+}
+
+// withSummary adds the summary bar (if any) to bars at its pinned position
+func (p *Progress) withSummary(bars []*Bar) []*Bar {
+	switch {
+	case p.summary == nil:
+		return bars
+	case p.summaryPos == SummaryTop:
+		return append([]*Bar{p.summary}, bars...)
+	default:
+		return append(bars, p.summary)
+	}
+}
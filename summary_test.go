@@ -0,0 +1,40 @@
+package cmpb_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nu11ptr/cmpb"
+)
+
+func TestSummary(t *testing.T) {
+	for _, pos := range []cmpb.SummaryPosition{cmpb.SummaryTop, cmpb.SummaryBottom} {
+		out := new(bytes.Buffer)
+		p := newTestProgress(out)
+		p.ShowSummary("TOTAL", pos)
+
+		p.NewBar("done", 10).Update(10)
+		p.NewBar("stopped", 10).Add(5)
+		p.Bar("stopped").Stop("", "")
+		running := p.NewBar("running", 20)
+		running.Add(5)
+
+		p.Start()
+		running.Stop("", "")
+		p.Wait()
+
+		lines := firstFrame(out)
+		if len(lines) != 4 {
+			t.Fatalf("want 4 lines got %d: %q", len(lines), lines)
+		}
+		summary := lines[0]
+		if pos == cmpb.SummaryBottom {
+			summary = lines[3]
+		}
+		// The time remaining isn't known until the rate has been measured
+		expected := "TOTAL     : 1 running, 1 done...       ETA ? [=========>----------]  50%"
+		if summary != expected {
+			t.Error("want", expected, "got", summary)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package cmpb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nu11ptr/cmpb/strutil"
+)
+
+func (p *Progress) renderExtMsg(lines []string, bar *Bar, l layout) []string {
+	extMsg := bar.extendedMsg()
+	if extMsg == "" {
+		return lines
+	}
+	for _, line := range strings.Split(extMsg, "\n") {
+		// Use spaces instead of tab so anything on screen is overwritten
+		lines = append(lines, p.fitLine(strutil.ResizeR("        "+line, p.param.Post, l.width)))
+	}
+	return lines
+}
+
+// fitLine makes sure a line can't wrap
+func (p *Progress) fitLine(line string) string {
+	if p.width > 0 {
+		line, _ = strutil.Truncate(line, p.width-1)
+	}
+	return line
+}
+
+// frame renders every line of a frame
+func (p *Progress) frame() []string {
+	reserved := 0
+	if p.summary != nil {
+		reserved = 1
+	}
+	bars, hidden := p.viewport(p.treeOrder(), reserved)
+	lines := make([]string, 0, len(bars)+2)
+	l := p.param.layout(p.width)
+	if p.summary != nil && p.summaryPos == SummaryTop {
+		lines = append(lines, p.fitLine(p.summary.render(l)))
+	}
+	for _, bar := range bars {
+		lines = append(lines, p.fitLine(bar.render(l)))
+		if p.param.InlineExtMsg {
+			lines = p.renderExtMsg(lines, bar, l)
+		}
+	}
+	// If not inline, we then rendor after all bars are rendered
+	if !p.param.InlineExtMsg {
+		for _, bar := range bars {
+			lines = p.renderExtMsg(lines, bar, l)
+		}
+	}
+	if hidden != "" {
+		// Pad it like every other line so a shorter summary overwrites a longer one
+		hidden = strutil.ResizeR(strings.Repeat(" ", p.param.PrePad)+hidden, p.param.Post, l.width)
+		lines = append(lines, p.fitLine(hidden))
+	}
+	if p.summary != nil && p.summaryPos == SummaryBottom {
+		lines = append(lines, p.fitLine(p.summary.render(l)))
+	}
+	return lines
+}
+
+// renderedRows returns how many rows the last frame now occupies, which can differ from the number
+// of lines when the terminal was made narrower and the lines were rewrapped
+func (p *Progress) renderedRows() int {
+	count := 0
+	for _, line := range p.prevFrame {
+		count += rows(strutil.Len(line), p.width)
+	}
+	return count
+}
+
+func (p *Progress) renderTerm() {
+	logs := append(p.takeRemovedLines(), p.logW.take()...)
+	lines := p.frame()
+
+	switch {
+	case len(p.prevFrame) == 0:
+		p.flushLogs(logs)
+		p.writeLines(lines)
+	case p.resized || len(logs) > 0:
+		// The old frame may have been rewrapped (or is about to be pushed down by log lines), so
+		// clear it entirely before drawing the new one
+		p.param.ScrollUp(p.renderedRows(), p.param.Out)
+		p.param.ClearDown(p.param.Out)
+		// Log lines go where the top of the old frame was, so they remain above the bars from now on
+		p.flushLogs(logs)
+		p.writeLines(lines)
+	case p.param.FullRedraw:
+		p.param.ScrollUp(p.renderedRows(), p.param.Out)
+		p.writeLines(lines)
+	default:
+		p.writeChanged(lines)
+	}
+	// Remove anything left over from a longer previous frame
+	if len(lines) < len(p.prevFrame) {
+		p.param.ClearDown(p.param.Out)
+	}
+	p.resized = false
+	p.prevFrame = lines
+}
+
+func (p *Progress) writeLines(lines []string) {
+	for _, line := range lines {
+		fmt.Fprintln(p.param.Out, line)
+	}
+}
+
+// writeChanged only rewrites the lines that differ from the previous frame, moving the cursor over
+// the ones that are the same. The cursor starts and ends just below the frame
+func (p *Progress) writeChanged(lines []string) {
+	prev := p.prevFrame
+	first := 0
+	for first < len(lines) && first < len(prev) && lines[first] == prev[first] {
+		first++
+	}
+	if first == len(lines) && first == len(prev) {
+		return
+	}
+	// Nothing was rewrapped, so each line of the previous frame is exactly one row
+	if first < len(prev) {
+		p.param.ScrollUp(len(prev)-first, p.param.Out)
+	}
+
+	skip := 0
+	for i := first; i < len(lines); i++ {
+		if i < len(prev) && lines[i] == prev[i] {
+			skip++
+			continue
+		}
+		if skip > 0 {
+			p.param.CursorDown(skip, p.param.Out)
+			skip = 0
+		}
+		fmt.Fprintln(p.param.Out, lines[i])
+	}
+	if skip > 0 {
+		p.param.CursorDown(skip, p.param.Out)
+	}
+}
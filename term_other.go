@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !solaris && !aix
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd,!solaris,!aix
+
+package cmpb
+
+import "os"
+
+// termSize returns the width and height of the terminal fd refers to, or zeros if it isn't a
+// terminal. The size can't be detected on this platform
+func termSize(fd uintptr) (int, int) {
+	return 0, 0
+}
+
+// notifyResize returns a channel that receives a value each time the terminal is resized, along
+// with a function to stop notifications. Resizes can't be detected on this platform
+func notifyResize() (<-chan os.Signal, func()) {
+	return nil, func() {}
+}
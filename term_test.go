@@ -0,0 +1,77 @@
+package cmpb_test
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/nu11ptr/cmpb"
+	"github.com/nu11ptr/cmpb/cmpbtest"
+)
+
+var scrollUpRe = regexp.MustCompile(`\x1b\[\d+A`)
+
+// bytesPerFrame renders many bars where only one changes and returns the average number of bytes
+// written per frame after the first
+func bytesPerFrame(t *testing.T, fullRedraw bool) int {
+	out := new(syncBuffer)
+	p := newTestProgress(out, func(p *cmpb.Param) { p.FullRedraw = fullRedraw })
+	for i := 0; i < 50; i++ {
+		p.NewBar(fmt.Sprintf("bar%d", i), 10).Update(10)
+	}
+	b := p.NewBar("changing", 50)
+
+	p.Start()
+	first := len(out.Bytes())
+	for i := 0; i < 50; i++ {
+		time.Sleep(time.Millisecond)
+		b.Increment()
+	}
+	p.Wait()
+
+	rest := out.Bytes()[first:]
+	frames := len(scrollUpRe.FindAll(rest, -1))
+	if frames == 0 {
+		t.Fatal("no frames rendered")
+	}
+	return len(rest) / frames
+}
+
+func TestDifferentialRender(t *testing.T) {
+	full, diff := bytesPerFrame(t, true), bytesPerFrame(t, false)
+	if diff*10 > full {
+		t.Errorf("want under %d bytes per frame, got %d", full/10, diff)
+	}
+}
+
+func TestGoldenFrames(t *testing.T) {
+	clock := cmpbtest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	term := cmpbtest.NewTerminal(60, 8)
+	p := newTestProgress(term, func(p *cmpb.Param) {
+		p.Clock, p.Width, p.Height, p.Interval = clock, 60, 8, time.Second
+	})
+	build := p.NewBar("build", 10)
+	test := p.NewBar("test", 20)
+	p.NewBar("lint", 0)
+	p.ShowSummary("total", cmpb.SummaryBottom)
+
+	p.Start()
+	cmpbtest.GoldenScreen(t, "testdata/start.golden", term)
+
+	build.SetMessage("compiling")
+	build.Add(5)
+	test.Add(5)
+	fmt.Fprintln(p.Writer(), "log: started tests")
+	clock.Frame(time.Second)
+	cmpbtest.GoldenScreen(t, "testdata/progress.golden", term)
+
+	p.RemoveBar("lint")
+	build.Succeed()
+	test.Fail(errors.New("2 tests failed"))
+	clock.Frame(time.Second)
+	cmpbtest.GoldenScreen(t, "testdata/done.golden", term)
+
+	p.Wait()
+}
@@ -0,0 +1,230 @@
+package cmpb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// Theme is a named set of colors for a bar that can be loaded from JSON. Each color is a space
+// separated list of any of: a color name (black, red, green, yellow, blue, magenta, cyan or white,
+// optionally prefixed with "hi" for the bright version), an RGB color as "#rrggbb" (downgraded to
+// the color depth of the terminal), bold, faint, italic or underline. An empty color leaves that
+// part of the bar uncolored
+type Theme struct {
+	Post, Key, KeyDiv, Msg, PreBar, LBracket, Empty, Full, Curr, RBracket, PostBar, StopMsg,
+	StopExtMsg string
+	Succeeded, Failed, Skipped, Cancelled string
+	// Gradient (if not empty) is a list of RGB colors the filled part of a running bar blends
+	// between as it completes, instead of Full and Curr
+	Gradient []string
+}
+
+// themeField is the color of part of a theme paired with where it goes in a BarColors
+type themeField struct {
+	spec string
+	f    *func(string, ...interface{}) string
+}
+
+func (t *Theme) fields(colors *BarColors) []themeField {
+	return []themeField{
+		{t.Post, &colors.Post}, {t.Key, &colors.Key}, {t.KeyDiv, &colors.KeyDiv},
+		{t.Msg, &colors.Msg}, {t.PreBar, &colors.PreBar}, {t.LBracket, &colors.LBracket},
+		{t.Empty, &colors.Empty}, {t.Full, &colors.Full}, {t.Curr, &colors.Curr},
+		{t.RBracket, &colors.RBracket}, {t.PostBar, &colors.PostBar}, {t.StopMsg, &colors.StopMsg},
+		{t.StopExtMsg, &colors.StopExtMsg}, {t.Succeeded, &colors.Succeeded},
+		{t.Failed, &colors.Failed}, {t.Skipped, &colors.Skipped}, {t.Cancelled, &colors.Cancelled},
+	}
+}
+
+// Validate returns an error describing the first color of the theme that can't be parsed (or nil
+// if they all can)
+func (t *Theme) Validate() error {
+	for _, field := range t.fields(new(BarColors)) {
+		if _, err := parseColor(field.spec, Color16); err != nil {
+			return err
+		}
+	}
+	for _, spec := range t.Gradient {
+		if _, err := parseRGB(spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Colors returns the colors of the theme using the given color depth. Any color that can't be
+// parsed is left uncolored, as is everything if the depth is ColorNone
+func (t *Theme) Colors(depth ColorDepth) *BarColors {
+	colors := DefaultColors()
+	if depth == ColorNone {
+		return colors
+	}
+	for _, field := range t.fields(colors) {
+		if attrs, err := parseColor(field.spec, depth); err == nil && len(attrs) > 0 {
+			*field.f = colorFunc(attrs)
+		}
+	}
+	if len(t.Gradient) > 0 {
+		stops := make([]RGB, 0, len(t.Gradient))
+		for _, spec := range t.Gradient {
+			if rgb, err := parseRGB(spec); err == nil {
+				stops = append(stops, rgb)
+			}
+		}
+		colors.Fill = Gradient(stops...)
+	}
+	return colors
+}
+
+var colorNames = map[string]color.Attribute{
+	"black": color.FgBlack, "red": color.FgRed, "green": color.FgGreen, "yellow": color.FgYellow,
+	"blue": color.FgBlue, "magenta": color.FgMagenta, "cyan": color.FgCyan, "white": color.FgWhite,
+	"bold": color.Bold, "faint": color.Faint, "italic": color.Italic, "underline": color.Underline,
+}
+
+// parseColor returns the attributes of a theme color using the given color depth
+func parseColor(spec string, depth ColorDepth) ([]color.Attribute, error) {
+	var attrs []color.Attribute
+	for _, word := range strings.Fields(strings.ToLower(spec)) {
+		if strings.HasPrefix(word, "#") {
+			rgb, err := parseRGB(word)
+			if err != nil {
+				return nil, err
+			}
+			attrs = append(attrs, rgb.attrs(depth)...)
+			continue
+		}
+		// The bright colors are 60 above the regular ones
+		name, offset := word, color.Attribute(0)
+		if strings.HasPrefix(word, "hi") {
+			name, offset = word[2:], color.FgHiBlack-color.FgBlack
+		}
+		attr, ok := colorNames[name]
+		if !ok || (offset != 0 && attr < color.FgBlack) {
+			return nil, fmt.Errorf("unknown color %q in %q", word, spec)
+		}
+		attrs = append(attrs, attr+offset)
+	}
+	return attrs, nil
+}
+
+// parseRGB parses an RGB color given as "#rrggbb"
+func parseRGB(spec string) (RGB, error) {
+	if len(spec) != 7 || spec[0] != '#' {
+		return RGB{}, fmt.Errorf("RGB color %q must be of the form #rrggbb", spec)
+	}
+	n, err := strconv.ParseUint(spec[1:], 16, 32)
+	if err != nil {
+		return RGB{}, fmt.Errorf("RGB color %q must be of the form #rrggbb", spec)
+	}
+	return RGB{uint8(n >> 16), uint8(n >> 8), uint8(n)}, nil
+}
+
+var (
+	themesMut sync.Mutex
+	themes    = map[string]*Theme{
+		// No colors at all
+		"plain": {},
+		// The colors of the demo in the README
+		"demo": {
+			Post: "hicyan", Key: "hiblue", KeyDiv: "hicyan", Msg: "hiyellow", PreBar: "himagenta",
+			LBracket: "hicyan", Empty: "hiyellow", Full: "higreen", Curr: "green",
+			RBracket: "hicyan", PostBar: "himagenta", Succeeded: "higreen", Failed: "hired",
+			Skipped: "hiblack", Cancelled: "hiyellow",
+		},
+		// Everything yellow until it succeeds (green) or fails (red)
+		"ryg": {
+			Post: "hiyellow", Key: "hiyellow", KeyDiv: "hiyellow", Msg: "hiyellow",
+			PreBar: "hiyellow", LBracket: "hiyellow", Empty: "hiyellow", Full: "hiyellow",
+			Curr: "hiyellow", RBracket: "hiyellow", PostBar: "hiyellow", StopMsg: "hiyellow",
+			StopExtMsg: "hiyellow", Succeeded: "higreen", Failed: "hired", Skipped: "hiblack",
+			Cancelled: "hiblack",
+		},
+		// A bar that goes from red through yellow to green as it completes
+		"gradient": {
+			Key: "bold", PreBar: "faint", PostBar: "bold", Succeeded: "#00ff00", Failed: "#ff0000",
+			Skipped: "faint", Cancelled: "faint", Gradient: []string{"#ff0000", "#ffff00", "#00ff00"},
+		},
+		// Only bold and underline, for terminals where colors are hard to read
+		"mono": {
+			Key: "bold", Full: "bold", Curr: "bold", PostBar: "bold", Failed: "bold underline",
+			Cancelled: "underline",
+		},
+	}
+)
+
+// RegisterTheme adds (or replaces) the theme with the given name, returning an error instead if
+// any of its colors can't be parsed
+func RegisterTheme(name string, theme *Theme) error {
+	if err := theme.Validate(); err != nil {
+		return fmt.Errorf("theme %q: %v", name, err)
+	}
+	t := *theme
+	t.Gradient = append([]string(nil), theme.Gradient...)
+
+	themesMut.Lock()
+	defer themesMut.Unlock()
+
+	themes[name] = &t
+	return nil
+}
+
+// LookupTheme returns a copy of the theme with the given name. The value is nil if it can't be
+// found
+func LookupTheme(name string) *Theme {
+	themesMut.Lock()
+	defer themesMut.Unlock()
+
+	theme, ok := themes[name]
+	if !ok {
+		return nil
+	}
+	t := *theme
+	t.Gradient = append([]string(nil), theme.Gradient...)
+	return &t
+}
+
+// Themes returns the names of all the registered themes in order
+func Themes() []string {
+	themesMut.Lock()
+	defer themesMut.Unlock()
+
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadTheme reads a theme from JSON, such as {"key": "hiblue", "full": "#00ff00"}
+func LoadTheme(r io.Reader) (*Theme, error) {
+	theme := new(Theme)
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(theme); err != nil {
+		return nil, fmt.Errorf("decoding theme: %v", err)
+	}
+	if err := theme.Validate(); err != nil {
+		return nil, err
+	}
+	return theme, nil
+}
+
+// SetTheme sets the colors of all the bars part of this progress (including those added later) to
+// those of the registered theme with the given name, using the color depth of the progress
+func (p *Progress) SetTheme(name string) error {
+	theme := LookupTheme(name)
+	if theme == nil {
+		return fmt.Errorf("unknown theme %q", name)
+	}
+	p.SetColors(theme.Colors(p.param.ColorDepth))
+	return nil
+}
@@ -0,0 +1,150 @@
+package cmpb_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nu11ptr/cmpb"
+)
+
+func TestColorDepth(t *testing.T) {
+	if err := cmpb.RegisterTheme("red-key", &cmpb.Theme{Key: "#ff0000"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name                            string
+		force, noColor, term, colorTerm string
+		output                          string
+	}{
+		{"NotTerminal", "", "", "xterm", "", "key"},
+		{"Force16", "1", "", "xterm", "", "\x1b[91mkey\x1b[0m"},
+		{"Force256", "true", "", "xterm-256color", "", "\x1b[38;5;196mkey\x1b[0m"},
+		{"ForceTrue", "1", "", "xterm", "truecolor", "\x1b[38;2;255;0;0mkey\x1b[0m"},
+		{"ForceLevel", "3", "", "dumb", "", "\x1b[38;2;255;0;0mkey\x1b[0m"},
+		{"ForceOverNoColor", "2", "1", "xterm", "", "\x1b[38;5;196mkey\x1b[0m"},
+		{"ForceOff", "0", "", "xterm", "truecolor", "key"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer setenv(map[string]string{
+				"FORCE_COLOR": test.force, "NO_COLOR": test.noColor, "TERM": test.term,
+				"COLORTERM": test.colorTerm,
+			})()
+			param := cmpb.DefaultParam()
+			param.Out = new(bytes.Buffer)
+			p := cmpb.NewWithParam(param)
+			if err := p.SetTheme("red-key"); err != nil {
+				t.Fatal(err)
+			}
+			// Bars added after the theme was set still use it
+			b := p.NewBar("key", 10)
+			if output := b.String(); !strings.HasPrefix(output, test.output) {
+				t.Errorf("want prefix %q got %q", test.output, output)
+			}
+		})
+	}
+}
+
+func TestSetColors(t *testing.T) {
+	colors := cmpb.DefaultColors()
+	colors.Key = func(s string, _ ...interface{}) string { return "<" + s + ">" }
+
+	tests := []struct {
+		name   string
+		depth  cmpb.ColorDepth
+		output string
+	}{
+		{"Colored", cmpb.Color16, "<b1>      : "},
+		{"None", cmpb.ColorNone, "b1        : "},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			param := cmpb.DefaultParam()
+			param.ColorDepth = test.depth
+			p := cmpb.NewWithParam(param)
+			b1 := p.NewBar("b1", 10)
+			p.SetColors(colors)
+			b2 := p.NewBar("b2", 10)
+
+			want := []string{test.output, strings.Replace(test.output, "b1", "b2", 1)}
+			for i, b := range []*cmpb.Bar{b1, b2} {
+				if output := b.String(); !strings.HasPrefix(output, want[i]) {
+					t.Errorf("want prefix %q got %q", want[i], output)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadTheme(t *testing.T) {
+	tests := []struct {
+		name, json string
+		theme      *cmpb.Theme
+	}{
+		{"Valid", `{"key": "hiblue bold", "full": "#00FF00", "gradient": ["#ff0000", "#00ff00"]}`,
+			&cmpb.Theme{Key: "hiblue bold", Full: "#00FF00", Gradient: []string{"#ff0000", "#00ff00"}}},
+		{"Empty", `{}`, &cmpb.Theme{}},
+		{"UnknownField", `{"bar": "red"}`, nil},
+		{"UnknownColor", `{"key": "purple"}`, nil},
+		{"BrightAttribute", `{"key": "hibold"}`, nil},
+		{"BadRGB", `{"gradient": ["#ff00"]}`, nil},
+		{"BadJSON", `{"key": `, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			theme, err := cmpb.LoadTheme(strings.NewReader(test.json))
+			if test.theme == nil {
+				if err == nil {
+					t.Error("want error got", theme)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(theme, test.theme) {
+				t.Errorf("want %+v got %+v", test.theme, theme)
+			}
+		})
+	}
+}
+
+func TestThemes(t *testing.T) {
+	names := strings.Join(cmpb.Themes(), " ")
+	for _, name := range []string{"demo", "gradient", "mono", "plain", "ryg"} {
+		if !strings.Contains(names, name) {
+			t.Error("want", name, "in", names)
+		}
+	}
+
+	if err := cmpb.RegisterTheme("bad", &cmpb.Theme{Full: "#zzzzzz"}); err == nil {
+		t.Error("want error registering an invalid theme")
+	}
+	if theme := cmpb.LookupTheme("bad"); theme != nil {
+		t.Error("want nil got", theme)
+	}
+	if err := cmpb.New().SetTheme("bad"); err == nil {
+		t.Error("want error setting an unknown theme")
+	}
+
+	// Changing a theme that was looked up doesn't change the registered one
+	theme := cmpb.LookupTheme("gradient")
+	theme.Gradient[0], theme.Key = "#000000", "red"
+	if theme := cmpb.LookupTheme("gradient"); theme.Gradient[0] != "#ff0000" || theme.Key != "bold" {
+		t.Error("want unchanged theme got", theme)
+	}
+
+	colors := cmpb.LookupTheme("gradient").Colors(cmpb.ColorTrue)
+	if colors.Fill == nil {
+		t.Fatal("want a gradient fill")
+	}
+	if output := colors.Fill(1, cmpb.ColorTrue)("x"); output != "\x1b[38;2;0;255;0mx\x1b[0m" {
+		t.Errorf("want %q got %q", "\x1b[38;2;0;255;0mx\x1b[0m", output)
+	}
+}
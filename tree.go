@@ -0,0 +1,183 @@
+package cmpb
+
+import "strings"
+
+// aggregateScale is the total given to a parent bar when its children have explicit weights
+const aggregateScale = 10000
+
+// NewChildBar creates a new progress bar nested beneath parent and adds it to the progress bar
+// collection. Once a bar has children, its status and total are calculated from them (so updating
+// it directly has no lasting effect) and it finishes once all of them have finished. If parent is
+// nil or not part of this progress (such as once it has been removed), the new bar is added at the
+// top level instead
+func (p *Progress) NewChildBar(parent *Bar, key string, total int64) *Bar {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	b := p.addBar(key, total)
+	if !p.hasBar(parent) {
+		return b
+	}
+	b.parent, b.depth = parent, parent.depth+1
+
+	parent.mut.Lock()
+	defer parent.mut.Unlock()
+
+	parent.children = append(parent.children, b)
+	return b
+}
+
+// hasBar returns true if b is one of the bars of this progress
+func (p *Progress) hasBar(b *Bar) bool {
+	for _, bar := range p.bars {
+		if bar == b {
+			return true
+		}
+	}
+	return false
+}
+
+// SetWeight sets how much this bar counts towards the progress of its parent. By default, children
+// are weighted by their totals, however once any child has an explicit weight, each child counts
+// towards the parent according to its weight (1 if not set) and the fraction of it complete
+func (b *Bar) SetWeight(weight float64) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.weight = weight
+}
+
+func (b *Bar) childBars() []*Bar {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	return b.children
+}
+
+// aggregate recalculates the status and total of the bar from its children (if it has any),
+// finishing it once they have all finished
+func (b *Bar) aggregate() {
+	children := b.childBars()
+	if len(children) == 0 {
+		return
+	}
+
+	infos := make([]barInfo, len(children))
+	weighted, allStopped, allCompleted := false, true, true
+	state := StateSkipped
+	for i, child := range children {
+		// Nested parents need to be up to date before we can use them
+		child.aggregate()
+		infos[i] = child.info()
+		weighted = weighted || infos[i].weight > 0
+		allStopped = allStopped && infos[i].stopped
+		allCompleted = allCompleted && infos[i].completed()
+		if stateRank[infos[i].state] > stateRank[state] {
+			state = infos[i].state
+		}
+	}
+
+	var curr, total int64
+	if weighted {
+		var sum, done float64
+		for i := range infos {
+			weight := infos[i].weight
+			if weight <= 0 {
+				weight = 1
+			}
+			sum += weight
+			done += weight * infos[i].fraction()
+		}
+		curr, total = int64(done/sum*aggregateScale), aggregateScale
+	} else {
+		for i := range infos {
+			if infos[i].total > 0 {
+				curr += minInt64(infos[i].curr, infos[i].total)
+				total += infos[i].total
+			}
+		}
+	}
+	if allCompleted {
+		curr = total
+	}
+
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if b.stopped {
+		return
+	}
+	// The total stays open until all the children have finished, since indeterminate children and
+	// those with an open total can still be running once the others add up to the total
+	b.total, b.openTotal = total, !allStopped
+	// Once all the children have finished, the state comes from them rather than from the total
+	if allStopped {
+		b.curr = curr
+		b.finish(state)
+		return
+	}
+	b.update(curr)
+}
+
+// stateRank decides the state of a parent from its children: the highest ranked state of any child
+// wins, so a parent only succeeds (or is skipped) if all its children did
+var stateRank = map[State]int{
+	StateSkipped: 0, StateSucceeded: 1, StateRunning: 2, StateStopped: 3, StateCancelled: 4,
+	StateFailed: 5,
+}
+
+// fraction returns how much of the bar is complete from 0 to 1
+func (i *barInfo) fraction() float64 {
+	switch {
+	case i.completed():
+		return 1
+	case i.total <= 0 || i.curr <= 0:
+		return 0
+	case i.curr >= i.total:
+		return 1
+	}
+	return float64(i.curr) / float64(i.total)
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// treeOrder returns all the visible bars with each one immediately followed by its children
+func (p *Progress) treeOrder() []*Bar {
+	bars := make([]*Bar, 0, len(p.bars))
+	var walk func(*Bar)
+	walk = func(b *Bar) {
+		// Hiding a bar hides its children too
+		if b.isHidden() {
+			return
+		}
+		bars = append(bars, b)
+		for _, child := range b.childBars() {
+			walk(child)
+		}
+	}
+	for _, b := range p.bars {
+		if b.parent == nil {
+			walk(b)
+		}
+	}
+	return bars
+}
+
+// aggregate recalculates all parent bars from their children
+func (p *Progress) aggregate() {
+	for _, b := range p.bars {
+		if b.parent == nil {
+			b.aggregate()
+		}
+	}
+}
+
+// indent returns the indent for a bar's key based on how deeply it is nested
+func (b *Bar) indent() string {
+	return strings.Repeat("  ", b.depth)
+}
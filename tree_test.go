@@ -0,0 +1,164 @@
+package cmpb_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nu11ptr/cmpb"
+)
+
+func TestChildBars(t *testing.T) {
+	tests := []struct {
+		name     string
+		weighted bool
+		expected []string
+	}{
+		{"ByTotal", false, []string{
+			"region    :                               0s [=========>----------]  50%",
+			"  host1   :                               0s [====================] 100%",
+			"    disk  :                               0s [====================] 100%",
+			"  host2   :                               0s [=====>--------------]  33%",
+			"other     :                               0s [--------------------]   0%",
+		}},
+		{"ByWeight", true, []string{
+			"region    :                               0s [==============>-----]  77%",
+			"  host1   :                               0s [====================] 100%",
+			"    disk  :                               0s [====================] 100%",
+			"  host2   :                               0s [=====>--------------]  33%",
+			"other     :                               0s [--------------------]   0%",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			p := newTestProgress(out)
+			region := p.NewBar("region", 0)
+			other := p.NewBar("other", 10)
+			host1 := p.NewChildBar(region, "host1", 0)
+			disk := p.NewChildBar(host1, "disk", 10)
+			host2 := p.NewChildBar(region, "host2", 30)
+			if test.weighted {
+				host1.SetWeight(2)
+			}
+			disk.Update(10)
+			host2.Update(10)
+
+			p.Start()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			host2.Update(30)
+			other.Update(10)
+			if err := p.WaitContext(ctx); err != nil {
+				t.Fatal("parent did not complete:", err)
+			}
+
+			first := strings.Join(firstFrame(out), "\n")
+			if first != strings.Join(test.expected, "\n") {
+				t.Errorf("want\n%s\ngot\n%s", strings.Join(test.expected, "\n"), first)
+			}
+		})
+	}
+}
+
+func TestChildStates(t *testing.T) {
+	tests := []struct {
+		name     string
+		children []func(b *cmpb.Bar)
+		state    cmpb.State
+	}{
+		{"Succeeded", []func(b *cmpb.Bar){(*cmpb.Bar).Succeed, (*cmpb.Bar).Skip}, cmpb.StateSucceeded},
+		{"Skipped", []func(b *cmpb.Bar){(*cmpb.Bar).Skip, (*cmpb.Bar).Skip}, cmpb.StateSkipped},
+		{"Failed", []func(b *cmpb.Bar){(*cmpb.Bar).Cancel, func(b *cmpb.Bar) { b.Fail(nil) }},
+			cmpb.StateFailed},
+		{"Cancelled", []func(b *cmpb.Bar){(*cmpb.Bar).Succeed, (*cmpb.Bar).Cancel},
+			cmpb.StateCancelled},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := newTestProgress(new(bytes.Buffer))
+			parent := p.NewBar("parent", 0)
+			for i, end := range test.children {
+				end(p.NewChildBar(parent, fmt.Sprint("child", i), 10))
+			}
+			p.Start()
+			p.Wait()
+			if state := parent.State(); state != test.state {
+				t.Error("want", test.state, "got", state)
+			}
+		})
+	}
+}
+
+func TestChildStillRunning(t *testing.T) {
+	tests := []struct {
+		name  string
+		start func(b *cmpb.Bar)
+	}{
+		{"Indeterminate", func(b *cmpb.Bar) {
+			b.SetTotal(0)
+			b.Add(3)
+		}},
+		{"OpenTotal", func(b *cmpb.Bar) {
+			b.SetOpenTotal(true)
+			b.Update(10)
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := newTestProgress(new(bytes.Buffer))
+			parent := p.NewBar("parent", 0)
+			p.NewChildBar(parent, "c1", 10).Update(10)
+			c2 := p.NewChildBar(parent, "c2", 10)
+			test.start(c2)
+
+			p.RenderFrame()
+			if state := parent.State(); state != cmpb.StateRunning {
+				t.Error("want", cmpb.StateRunning, "got", state)
+			}
+
+			c2.Succeed()
+			p.RenderFrame()
+			if state := parent.State(); state != cmpb.StateSucceeded {
+				t.Error("want", cmpb.StateSucceeded, "got", state)
+			}
+		})
+	}
+}
+
+func TestOrphanChildBar(t *testing.T) {
+	p := newTestProgress(new(bytes.Buffer))
+	removed := p.NewBar("removed", 10)
+	p.RemoveBar("removed")
+	tests := []struct {
+		name   string
+		parent *cmpb.Bar
+	}{
+		{"Nil", nil},
+		{"Removed", removed},
+		{"OtherProgress", cmpb.New().NewBar("other", 10)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := p.NewChildBar(test.parent, "orphan", 10)
+			b.Update(5)
+
+			// It is added at the top level instead
+			expected := "orphan    :                               0s [=========>----------]  50%"
+			if frame := p.RenderFrame(); !strings.Contains(frame, "\n"+expected) &&
+				!strings.HasPrefix(frame, expected) {
+				t.Errorf("want %q in %q", expected, frame)
+			}
+			if test.parent != nil && test.parent.State() != cmpb.StateRunning {
+				t.Error("want", cmpb.StateRunning, "got", test.parent.State())
+			}
+		})
+	}
+}
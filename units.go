@@ -0,0 +1,42 @@
+package cmpb
+
+import (
+	"time"
+
+	"github.com/nu11ptr/cmpb/strutil"
+)
+
+// CalcBytes treats the status of the bar as a number of bytes and returns a string of the bytes
+// transferred so far and the total (e.g. "12.3 MiB / 1.2 GiB") using the given units and precision
+func CalcBytes(units strutil.Units, prec int) func(int64, int64, time.Time, bool) string {
+	return func(curr, total int64, start time.Time, stopped bool) string {
+		if total <= 0 {
+			return strutil.FmtBytes(curr, units, prec)
+		}
+		return strutil.FmtBytes(curr, units, prec) + " / " + strutil.FmtBytes(total, units, prec)
+	}
+}
+
+// CalcCount returns a string of the steps completed so far and the total using k/M/G suffixes
+// (e.g. "4.5k/1.2M") with the given precision
+func CalcCount(prec int) func(int64, int64, time.Time, bool) string {
+	return func(curr, total int64, start time.Time, stopped bool) string {
+		if total <= 0 {
+			return strutil.FmtCount(curr, prec)
+		}
+		return strutil.FmtCount(curr, prec) + "/" + strutil.FmtCount(total, prec)
+	}
+}
+
+// CalcByteRate calculates the throughput in bytes per second and returns a string (e.g. "4.5 MB/s")
+// using the given units and precision. Like CalcETA, each decorator created gets its own estimator
+// from newEst
+func CalcByteRate(newEst func() Estimator, units strutil.Units, prec int) DecoratorFactory {
+	return func(clock Clock) func(int64, int64, time.Time, bool) string {
+		s := &sampler{est: newEst(), clock: clock}
+
+		return func(curr, total int64, start time.Time, stopped bool) string {
+			return strutil.FmtBytes(int64(s.rate(curr, stopped)), units, prec) + "/s"
+		}
+	}
+}
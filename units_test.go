@@ -0,0 +1,47 @@
+package cmpb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nu11ptr/cmpb"
+	"github.com/nu11ptr/cmpb/cmpbtest"
+	"github.com/nu11ptr/cmpb/strutil"
+)
+
+func TestCalcBytes(t *testing.T) {
+	tests := []struct {
+		name        string
+		curr, total int64
+		output      string
+	}{
+		{"Known", 12*1024*1024 + 300*1024, 1288490189, "12.3 MiB / 1.2 GiB"},
+		{"Unknown", 2048, 0, "2.0 KiB"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := cmpb.CalcBytes(strutil.IEC, 1)(test.curr, test.total, time.Now(), false)
+			if output != test.output {
+				t.Error("want", test.output, "got", output)
+			}
+		})
+	}
+}
+
+func TestCalcCount(t *testing.T) {
+	output := cmpb.CalcCount(1)(4500, 1200000, time.Now(), false)
+	if output != "4.5k/1.2M" {
+		t.Error("want", "4.5k/1.2M", "got", output)
+	}
+}
+
+func TestCalcByteRate(t *testing.T) {
+	clock := cmpbtest.NewClock(time.Now())
+	f := cmpb.CalcByteRate(newAvg, strutil.SI, 1)(clock)
+	f(0, 0, clock.Now(), false)
+	clock.Advance(10 * time.Second)
+	if output := f(45000000, 0, clock.Now(), false); output != "4.5 MB/s" {
+		t.Error("want", "4.5 MB/s", "got", output)
+	}
+}
@@ -0,0 +1,108 @@
+package cmpb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// barInfo is a consistent snapshot of the state of a bar
+type barInfo struct {
+	key                      string
+	curr, total              int64
+	stopped, lastRender      bool
+	state                    State
+	start, changed, finished time.Time
+	extLines                 int
+	weight                   float64
+}
+
+func (b *Bar) info() barInfo {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	info := barInfo{
+		key: b.key, curr: b.curr, total: b.total, stopped: b.stopped, lastRender: b.lastRender,
+		state: b.state, start: b.start, changed: b.changed, finished: b.finished, weight: b.weight,
+	}
+	if b.extMsg != "" {
+		info.extLines = strings.Count(b.extMsg, "\n") + 1
+	}
+	return info
+}
+
+// completed returns true if the bar finished without anything going wrong (succeeded or skipped)
+func (i *barInfo) completed() bool {
+	return i.state == StateSucceeded || i.state == StateSkipped
+}
+
+// viewport returns the bars that fit within the output height (in their original order) along with
+// a summary line for the ones that don't. Running bars are preferred, followed by the ones that
+// changed most recently. If all the bars fit, they are all returned along with an empty summary
+func (p *Progress) viewport(bars []*Bar, reserved int) ([]*Bar, string) {
+	if !p.param.Viewport || p.height <= 0 {
+		return bars, ""
+	}
+	// The cursor sits on the row below the frame, so that row can't be used
+	maxRows := p.height - 1 - reserved
+	infos := make([]barInfo, len(bars))
+	needed := 0
+	for i, bar := range bars {
+		infos[i] = bar.info()
+		needed += 1 + infos[i].extLines
+	}
+	if needed <= maxRows {
+		return bars, ""
+	}
+
+	order := make([]int, len(bars))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := &infos[order[i]], &infos[order[j]]
+		if a.stopped != b.stopped {
+			return !a.stopped
+		}
+		return a.changed.After(b.changed)
+	})
+
+	// Save a row for the summary line
+	budget := maxRows - 1
+	show := make([]bool, len(bars))
+	for _, i := range order {
+		if rows := 1 + infos[i].extLines; rows <= budget {
+			show[i] = true
+			budget -= rows
+		}
+	}
+
+	visible := make([]*Bar, 0, len(bars))
+	counts := make(map[State]int, len(states))
+	for i, bar := range bars {
+		if show[i] {
+			visible = append(visible, bar)
+		} else {
+			counts[infos[i].state]++
+		}
+	}
+	hidden := len(bars) - len(visible)
+	return visible, fmt.Sprintf("+%d more (%s)", hidden, fmtOutcomes(counts))
+}
+
+type count struct {
+	n    int
+	name string
+}
+
+// fmtCounts formats a list of counts, leaving out any that are zero (e.g. "3 running, 1 done")
+func fmtCounts(counts ...count) string {
+	var parts []string
+	for _, c := range counts {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.n, c.name))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,67 @@
+package cmpb_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nu11ptr/cmpb"
+	"github.com/nu11ptr/cmpb/cmpbtest"
+)
+
+func TestViewport(t *testing.T) {
+	out := new(bytes.Buffer)
+	p := newTestProgress(out, func(p *cmpb.Param) { p.Height = 6 })
+
+	for i := 0; i < 10; i++ {
+		b := p.NewBar(fmt.Sprintf("bar%d", i), 10)
+		switch {
+		case i < 3:
+			b.Update(10)
+		case i < 5:
+			b.Fail(nil)
+		case i == 9:
+			b.SetMessage("latest")
+		}
+	}
+
+	p.Start()
+	p.Stop("", "")
+	p.Wait()
+
+	lines := firstFrame(out)
+	if len(lines) != 5 {
+		t.Fatalf("want 5 lines got %d: %q", len(lines), lines)
+	}
+	if !strings.HasPrefix(lines[3], "bar9      : latest") {
+		t.Error("most recently changed bar not shown:", lines[3])
+	}
+	expected := "+6 more (1 running, 3 done, 2 failed)"
+	if strings.TrimRight(lines[4], " ") != expected {
+		t.Error("want", expected, "got", lines[4])
+	}
+}
+
+func TestViewportShrinkingSummary(t *testing.T) {
+	term := cmpbtest.NewTerminal(80, 6)
+	p := newTestProgress(term, func(p *cmpb.Param) {
+		p.Refresh, p.Width, p.Height = cmpb.RefreshManual, 80, 5
+	})
+
+	var bars []*cmpb.Bar
+	for i := 0; i < 10; i++ {
+		bars = append(bars, p.NewBar(fmt.Sprintf("bar%d", i), 10))
+	}
+	p.Render()
+	for _, b := range bars {
+		b.Update(10)
+	}
+	p.Render()
+
+	screen := term.Screen()
+	expected := "+7 more (7 done)"
+	if screen[3] != expected {
+		t.Error("want", expected, "got", screen[3])
+	}
+}